@@ -0,0 +1,143 @@
+package main
+
+import (
+    "os"
+    "fmt"
+    "flag"
+    "io/ioutil"
+    "encoding/json"
+    "github.com/ltkh/jiramanager/internal/config"
+    "github.com/ltkh/jiramanager/internal/api/v1"
+)
+
+// runCommand runs a subcommand and returns the exit code
+func runCommand(args []string) int {
+    switch args[0] {
+        case "check-config":
+            return checkConfig(args[1:])
+        case "check-jira":
+            return checkJira(args[1:])
+        case "template":
+            if len(args) > 1 && args[1] == "test" {
+                return templateTest(args[2:])
+            }
+    }
+
+    fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+    fmt.Fprintln(os.Stderr, "commands:")
+    fmt.Fprintln(os.Stderr, "  check-config --config FILE")
+    fmt.Fprintln(os.Stderr, "  check-jira --config FILE")
+    fmt.Fprintln(os.Stderr, "  template test --config FILE --receiver NAME --alert FILE")
+    return 2
+}
+
+// checkConfig reports all problems found in the configuration file
+func checkConfig(args []string) int {
+    fs := flag.NewFlagSet("check-config", flag.ContinueOnError)
+    cfFile := fs.String("config", "config/config.yml", "config file")
+    if err := fs.Parse(args); err != nil {
+        return 2
+    }
+
+    _, errs := config.Load(*cfFile)
+    if len(errs) > 0 {
+        for _, err := range errs {
+            fmt.Fprintf(os.Stderr, "[error] %v\n", err)
+        }
+        fmt.Fprintf(os.Stderr, "%s: %d problem(s) found\n", *cfFile, len(errs))
+        return 1
+    }
+
+    fmt.Printf("%s: config is valid\n", *cfFile)
+    return 0
+}
+
+// checkJira verifies the receivers against the Jira create metadata
+func checkJira(args []string) int {
+    fs := flag.NewFlagSet("check-jira", flag.ContinueOnError)
+    cfFile := fs.String("config", "config/config.yml", "config file")
+    if err := fs.Parse(args); err != nil {
+        return 2
+    }
+
+    cfg, err := config.New(*cfFile)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "[error] %v\n", err)
+        return 1
+    }
+
+    errs := v1.CheckJira(cfg)
+    if len(errs) > 0 {
+        for _, err := range errs {
+            fmt.Fprintf(os.Stderr, "[error] %v\n", err)
+        }
+        fmt.Fprintf(os.Stderr, "%s: %d problem(s) found\n", *cfFile, len(errs))
+        return 1
+    }
+
+    fmt.Printf("%s: receivers match the Jira metadata\n", *cfFile)
+    return 0
+}
+
+// templateTest renders the issues a receiver would create for a sample alert payload
+func templateTest(args []string) int {
+    fs := flag.NewFlagSet("template test", flag.ContinueOnError)
+    cfFile   := fs.String("config", "config/config.yml", "config file")
+    receiver := fs.String("receiver", "", "receiver name, taken from the payload when empty")
+    alert    := fs.String("alert", "", "file with an alert or an Alertmanager webhook payload, - for stdin")
+    if err := fs.Parse(args); err != nil {
+        return 2
+    }
+
+    cfg, err := config.New(*cfFile)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "[error] %v\n", err)
+        return 1
+    }
+
+    _, templates, err := cfg.LoadTemplates()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "[error] %v\n", err)
+        return 1
+    }
+
+    var body []byte
+    if *alert == "" || *alert == "-" {
+        body, err = ioutil.ReadAll(os.Stdin)
+    } else {
+        body, err = ioutil.ReadFile(*alert)
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "[error] %v\n", err)
+        return 1
+    }
+
+    name, alerts, err := v1.Alerts(body)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "[error] %v\n", err)
+        return 1
+    }
+    if *receiver != "" {
+        name = *receiver
+    }
+
+    previews, err := v1.RenderPreview(cfg, templates, name, alerts)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "[error] %v\n", err)
+        return 1
+    }
+
+    jsn, err := json.MarshalIndent(previews, "", "  ")
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "[error] %v\n", err)
+        return 1
+    }
+    fmt.Println(string(jsn))
+
+    for _, p := range previews {
+        if p.Error != "" {
+            return 1
+        }
+    }
+    return 0
+}
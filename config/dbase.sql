@@ -1,4 +1,4 @@
-create table if not exists mon_issues (
+create table if not exists issues (
   `group_id`      varchar(50) not null,
   `status_id`     varchar(50) default '',
   `status_name`   varchar(100) default '',
@@ -7,6 +7,49 @@ create table if not exists mon_issues (
   `issue_self`    varchar(1500),
   `created`       bigint(20) default 0,
   `updated`       bigint(20) default 0,
-  `template`      varchar(250), 
-  unique key IDX_mon_issues_group_id (group_id)
+  `template`      varchar(250) default '',
+  `receiver`      varchar(250) default '',
+  `link_key`      varchar(1500) default '',
+  `escalation`    int default 0,
+  `labels`        text,
+  `silence_id`    varchar(50) default '',
+  `silence_ends`  bigint(20) default 0,
+  unique key IDX_issues_group_id (group_id)
+) engine InnoDB default charset=utf8mb4 collate=utf8mb4_unicode_ci;
+
+create table if not exists issue_events (
+  `id`            bigint(20) not null auto_increment,
+  `group_id`      varchar(50),
+  `issue_key`     varchar(50) default '',
+  `fingerprint`   varchar(50) default '',
+  `event`         varchar(50),
+  `details`       varchar(1500) default '',
+  `created`       bigint(20) default 0,
+  primary key (id),
+  key IDX_issue_events_group_id (group_id),
+  key IDX_issue_events_issue_key (issue_key),
+  key IDX_issue_events_created (created)
+) engine InnoDB default charset=utf8mb4 collate=utf8mb4_unicode_ci;
+
+create table if not exists dry_runs (
+  `id`            bigint(20) not null auto_increment,
+  `group_id`      varchar(50),
+  `receiver`      varchar(250) default '',
+  `fingerprint`   varchar(50) default '',
+  `payload`       mediumtext,
+  `created`       bigint(20) default 0,
+  primary key (id),
+  key IDX_dry_runs_group_id (group_id),
+  key IDX_dry_runs_created (created)
+) engine InnoDB default charset=utf8mb4 collate=utf8mb4_unicode_ci;
+
+create table if not exists silences (
+  `id`            bigint(20) not null auto_increment,
+  `matchers`      text,
+  `created_by`    varchar(250) default '',
+  `comment`       varchar(1500) default '',
+  `ends_at`       bigint(20) default 0,
+  `created`       bigint(20) default 0,
+  primary key (id),
+  key IDX_silences_ends_at (ends_at)
 ) engine InnoDB default charset=utf8mb4 collate=utf8mb4_unicode_ci;
@@ -3,11 +3,13 @@ module github.com/ltkh/jiramanager
 go 1.14
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.0
 	github.com/andygrunwald/go-jira v1.13.0
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/ltkh/alertstrap v1.5.1 // indirect
 	github.com/mattn/go-sqlite3 v1.14.9
 	github.com/naoina/toml v0.1.1 // indirect
+	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0
 	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,147 @@
+package v1
+
+import (
+    "fmt"
+    "log"
+    "net/url"
+    "strings"
+    "encoding/json"
+    "github.com/andygrunwald/go-jira"
+    "github.com/ltkh/jiramanager/internal/config"
+    "github.com/ltkh/jiramanager/internal/template"
+)
+
+// aggregateGroup returns the group id of the summary issue of the receiver
+func aggregateGroup(receiver string) string {
+    return getHash("aggregate:" + receiver)
+}
+
+// aggregateText returns the Markdown list of the throttled alerts with their rendered summaries
+func aggregateText(receiver *config.Receiver, tmpl *template.Template, alerts []pendingAlert) string {
+    var lines []string
+    for _, pa := range alerts {
+        summary, err := tmpl.Execute(receiver.Summary, pa.alert)
+        if err != nil || strings.TrimSpace(summary) == "" {
+            labels, _ := json.Marshal(pa.alert["labels"])
+            summary = string(labels)
+        }
+        lines = append(lines, "- " + summary)
+    }
+    return strings.Join(lines, "\n")
+}
+
+// addComment adds a comment to an existing issue
+func addComment(self string, tp jira.BasicAuthTransport, key, body string) error {
+    u, err := url.Parse(self)
+    if err != nil {
+        return err
+    }
+
+    jiraClient, err := jira.NewClient(tp.Client(), fmt.Sprintf("%s://%s", u.Scheme, u.Host))
+    if err != nil {
+        return err
+    }
+
+    // Issue.AddComment also sends the empty author and visibility objects
+    req, err := jiraClient.NewRequest("POST", "rest/api/2/issue/" + key + "/comment", map[string]string{"body": body})
+    if err != nil {
+        return err
+    }
+
+    resp, err := jiraClient.Do(req, nil)
+    if err != nil {
+        return jira.NewJiraError(resp, err)
+    }
+    return nil
+}
+
+// reportAlerts creates the issue of the group listing the alerts, or comments on it while it is open,
+// and returns the issue, which is not found in dry run mode. An error means that the alerts were
+// not reported and should be reported again.
+func (api *Api) reportAlerts(cfg *config.Config, templates map[string]*template.Template, receiver *config.Receiver, group_id, summary, intro, commentIntro string, alerts []pendingAlert) (config.Issue, bool, error) {
+    text := aggregateText(receiver, templates[receiver.Name], alerts)
+    details := fmt.Sprintf("%d alerts", len(alerts))
+
+    tp := jira.BasicAuthTransport{
+        Username: cfg.Defaults.User,
+        Password: cfg.Defaults.Password,
+    }
+
+    task, found, err := api.Client.LoadIssue(group_id)
+    if err != nil {
+        return task, false, fmt.Errorf("load issue %v", err)
+    }
+
+    if found && !api.isResolveState(task.StatusId) {
+        body := commentIntro + "\n\n" + text
+        if receiver.DescriptionFormat == template.FormatWiki {
+            body = template.MarkdownToWiki(body)
+        }
+        if err := addComment(task.IssueSelf, tp, task.IssueKey, body); err != nil {
+            return task, false, fmt.Errorf("comment issue %s: %v", task.IssueKey, err)
+        }
+        log.Printf("[info] comment issue: %s, %s", task.IssueKey, details)
+        api.saveEvent(config.EventCommented, group_id, task.IssueKey, details, nil)
+        return task, true, nil
+    }
+
+    desc := intro + "\n\n" + text
+
+    var issueADF map[string]interface{}
+    switch receiver.DescriptionFormat {
+        case template.FormatWiki:
+            desc = template.MarkdownToWiki(desc)
+        case template.FormatADF:
+            issueADF = template.MarkdownToADF(desc)
+    }
+    is := newIssue(receiver, summary, desc)
+
+    if api.isDryRun(receiver) {
+        if err := api.saveDryRun(receiver, group_id, is, issueADF, nil); err != nil {
+            log.Printf("[error] save dry run %v", err)
+        }
+        return task, false, nil
+    }
+
+    is, err = createIssue(receiver.ApiUrl, tp, is, issueADF)
+    if err != nil {
+        return task, false, fmt.Errorf("create issue %v", err)
+    }
+
+    tk := config.Issue{
+        GroupId:    group_id,
+        Receiver:   receiver.Name,
+        IssueId:    is.ID,
+        IssueKey:   is.Key,
+        IssueSelf:  is.Self,
+    }
+    log.Printf("[info] create issue: %s, %s", is.Key, details)
+    api.saveEvent(config.EventCreated, group_id, is.Key, details, nil)
+
+    // The alerts are reported, without the record the next report creates another issue
+    if err := api.Client.SaveIssue(tk); err != nil {
+        log.Printf("[error] save issue %s: %v", is.Key, err)
+    }
+    return tk, true, nil
+}
+
+// flushAggregated reports the alerts throttled for the receiver in one summary issue,
+// which is commented on while it is open instead of creating another one
+func (api *Api) flushAggregated(cfg *config.Config, templates map[string]*template.Template, receiver *config.Receiver) {
+    alerts := api.limits.takeAggregated(receiver.Name)
+    if len(alerts) == 0 {
+        return
+    }
+
+    _, _, err := api.reportAlerts(cfg, templates, receiver, aggregateGroup(receiver.Name),
+        fmt.Sprintf("%d alerts throttled by the rate limits of receiver %s", len(alerts), receiver.Name),
+        "The issues of the following alerts were not created because the rate limits were exceeded:",
+        "More alerts were throttled by the rate limits:",
+        alerts)
+    if err != nil {
+        // The alerts are reported with the next flush
+        log.Printf("[warn] report %d aggregated alerts of receiver %s: %v, retrying", len(alerts), receiver.Name, err)
+        dropped := api.limits.restoreAggregated(receiver.Name, alerts)
+        api.limits.countRestoreDropped(receiver.Name, "waiting for the summary issue", dropped)
+    }
+}
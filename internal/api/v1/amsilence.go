@@ -0,0 +1,173 @@
+package v1
+
+import (
+    "fmt"
+    "log"
+    "time"
+    "bytes"
+    "strings"
+    "net/http"
+    "io/ioutil"
+    "encoding/json"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+type amMatcher struct {
+    Name         string                       `json:"name"`
+    Value        string                       `json:"value"`
+    IsRegex      bool                         `json:"isRegex"`
+    IsEqual      bool                         `json:"isEqual"`
+}
+
+type amSilence struct {
+    Id           string                       `json:"id,omitempty"`
+    Matchers     []amMatcher                  `json:"matchers"`
+    StartsAt     time.Time                    `json:"startsAt"`
+    EndsAt       time.Time                    `json:"endsAt"`
+    CreatedBy    string                       `json:"createdBy"`
+    Comment      string                       `json:"comment"`
+}
+
+// alertLabels returns the string labels of the alert
+func alertLabels(alert map[string]interface{}) map[string]string {
+    labels, _ := alert["labels"].(map[string]interface{})
+    result := make(map[string]string, len(labels))
+    for name, value := range labels {
+        if v, ok := value.(string); ok {
+            result[name] = v
+        }
+    }
+    return result
+}
+
+// amRequest sends a request to the Alertmanager v2 API and decodes the response into v
+func amRequest(ams *config.AlertmanagerSilence, method, path string, body, v interface{}) error {
+    var data []byte
+    if body != nil {
+        var err error
+        if data, err = json.Marshal(body); err != nil {
+            return err
+        }
+    }
+
+    req, err := http.NewRequest(method, strings.TrimRight(ams.Url, "/") + path, bytes.NewReader(data))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    switch {
+        case ams.User != "":
+            req.SetBasicAuth(ams.User, ams.Password)
+        case ams.BearerToken != "":
+            req.Header.Set("Authorization", "Bearer " + ams.BearerToken)
+    }
+
+    client := &http.Client{ Timeout: ams.Timeout }
+    resp, err := client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    content, err := ioutil.ReadAll(resp.Body)
+    if err != nil {
+        return err
+    }
+    if resp.StatusCode != 200 {
+        return fmt.Errorf("alertmanager %s %s: %s %s", method, path, resp.Status, strings.TrimSpace(string(content)))
+    }
+    if v != nil {
+        return json.Unmarshal(content, v)
+    }
+    return nil
+}
+
+// postAmSilence creates or extends the Alertmanager silence of the issue labels and returns its id
+func postAmSilence(ams *config.AlertmanagerSilence, i config.Issue, now time.Time) (string, error) {
+    sl := amSilence{
+        Id:         i.SilenceId,
+        StartsAt:   now,
+        EndsAt:     now.Add(ams.Duration),
+        CreatedBy:  ams.CreatedBy,
+        Comment:    fmt.Sprintf("Jira issue %s is %s", i.IssueKey, i.StatusName),
+    }
+    for name, value := range i.Labels {
+        sl.Matchers = append(sl.Matchers, amMatcher{ Name: name, Value: value, IsEqual: true })
+    }
+
+    var result struct {
+        SilenceId    string                   `json:"silenceID"`
+    }
+    if err := amRequest(ams, "POST", "/api/v2/silences", sl, &result); err != nil {
+        return "", err
+    }
+    return result.SilenceId, nil
+}
+
+// syncAmSilence silences the alerts of the issue in Alertmanager once it moves into one of the
+// configured statuses, extends the silence while the issue is open and expires it on resolve
+func (api *Api) syncAmSilence(cfg *config.Config, i config.Issue) {
+    receiver := cfg.GetReceiver(i.Receiver)
+    if receiver == nil || receiver.AlertmanagerSilence == nil {
+        return
+    }
+    ams := receiver.AlertmanagerSilence
+    now := time.Now().UTC()
+
+    // Issues stored before the alert labels were recorded, parent and umbrella issues have no labels to silence
+    if len(i.Labels) == 0 {
+        if i.SilenceId == "" && matchStatus(i, ams.Status) && !api.isResolveState(i.StatusId) && api.recorded.first(i.GroupId, "no labels") {
+            log.Printf("[info] issue %s has no stored alert labels, its alerts are not silenced in alertmanager", i.IssueKey)
+        }
+        return
+    }
+
+    if api.isResolveState(i.StatusId) {
+        if i.SilenceId == "" {
+            return
+        }
+        if err := amRequest(ams, "DELETE", "/api/v2/silence/" + i.SilenceId, nil, nil); err != nil {
+            log.Printf("[error] expire alertmanager silence of %s: %v", i.IssueKey, err)
+            return
+        }
+        if err := api.Client.UpdateSilence(i.GroupId, "", 0); err != nil {
+            log.Printf("[error] %v", err)
+            return
+        }
+        log.Printf("[info] alertmanager silence %s of %s expired", i.SilenceId, i.IssueKey)
+        api.saveEvent(config.EventAmExpired, i.GroupId, i.IssueKey, i.SilenceId, nil)
+        return
+    }
+
+    if i.SilenceId == "" && !matchStatus(i, ams.Status) {
+        return
+    }
+    // The silence is extended once half of its duration has passed
+    if i.SilenceId != "" && time.Unix(i.SilenceEnds, 0).Sub(now) > ams.Duration / 2 {
+        return
+    }
+
+    id, err := postAmSilence(ams, i, now)
+    if err != nil && i.SilenceId != "" {
+        // The silence may have been deleted in Alertmanager meanwhile
+        prev := i
+        prev.SilenceId = ""
+        id, err = postAmSilence(ams, prev, now)
+    }
+    if err != nil {
+        log.Printf("[error] silence alerts of %s: %v", i.IssueKey, err)
+        return
+    }
+
+    if err := api.Client.UpdateSilence(i.GroupId, id, now.Add(ams.Duration).Unix()); err != nil {
+        log.Printf("[error] %v", err)
+        return
+    }
+
+    if i.SilenceId == "" {
+        log.Printf("[info] alertmanager silence %s of %s created", id, i.IssueKey)
+        api.saveEvent(config.EventAmSilenced, i.GroupId, i.IssueKey, id, nil)
+    } else {
+        log.Printf("[info] alertmanager silence %s of %s extended", id, i.IssueKey)
+    }
+}
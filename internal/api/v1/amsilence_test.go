@@ -0,0 +1,142 @@
+package v1
+
+import (
+    "time"
+    "testing"
+    "strings"
+    "net/http"
+    "io/ioutil"
+    "encoding/json"
+    "net/http/httptest"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+// amSilenceDb records the stored Alertmanager silences of the issues
+type amSilenceDb struct {
+    fakeDb
+    silenceId    string
+    silenceEnds  int64
+}
+
+func (f *amSilenceDb) UpdateSilence(group_id, silence_id string, ends int64) error {
+    f.silenceId = silence_id
+    f.silenceEnds = ends
+    return nil
+}
+
+func TestSyncAmSilence(t *testing.T) {
+    var requests []string
+    var posted []amSilence
+    am := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+            t.Errorf("%s %s: Authorization %q, want the bearer token", r.Method, r.URL.Path, auth)
+        }
+        requests = append(requests, r.Method + " " + r.URL.Path)
+        if r.Method == "POST" {
+            body, _ := ioutil.ReadAll(r.Body)
+            var sl amSilence
+            if err := json.Unmarshal(body, &sl); err != nil {
+                t.Errorf("POST %s: %v", r.URL.Path, err)
+            }
+            posted = append(posted, sl)
+            w.Write([]byte(`{"silenceID":"s1"}`))
+        }
+    }))
+    defer am.Close()
+
+    ams := &config.AlertmanagerSilence{
+        Url:         am.URL,
+        Status:      []string{"In Progress"},
+        Duration:    time.Hour,
+        CreatedBy:   "jiramanager",
+        Timeout:     time.Second,
+        BearerToken: "secret",
+    }
+    cfg := &config.Config{
+        Defaults:   &config.Defaults{ ResolveState: []string{"5"} },
+        Receivers:  []*config.Receiver{ &config.Receiver{ Name: "jira", AlertmanagerSilence: ams } },
+    }
+    client := &amSilenceDb{}
+    api := &Api{ Client: client, Config: cfg, recorded: newEventRecorder() }
+
+    issue := config.Issue{
+        GroupId:    "g1",
+        IssueKey:   "TEST-1",
+        Receiver:   "jira",
+        StatusId:   "3",
+        StatusName: "In Progress",
+        Labels:     map[string]string{ "alertname": "up" },
+    }
+
+    // Create
+    api.syncAmSilence(cfg, issue)
+    if len(posted) != 1 || posted[0].Id != "" {
+        t.Fatalf("create: posted %+v, want one new silence", posted)
+    }
+    if m := posted[0].Matchers; len(m) != 1 || m[0] != (amMatcher{ Name: "alertname", Value: "up", IsEqual: true }) {
+        t.Errorf("create: matchers %+v", m)
+    }
+    if client.silenceId != "s1" || client.silenceEnds <= time.Now().Unix() {
+        t.Errorf("create: stored silence %q ending %d", client.silenceId, client.silenceEnds)
+    }
+    if len(client.events) != 1 || client.events[0].Event != config.EventAmSilenced {
+        t.Errorf("create: events %+v", client.events)
+    }
+
+    // Not extended before half of the duration has passed
+    issue.SilenceId = client.silenceId
+    issue.SilenceEnds = client.silenceEnds
+    api.syncAmSilence(cfg, issue)
+    if len(requests) != 1 {
+        t.Errorf("extend: requests %v, the silence must not be extended yet", requests)
+    }
+
+    // Extend
+    issue.SilenceEnds = time.Now().Add(10 * time.Minute).Unix()
+    api.syncAmSilence(cfg, issue)
+    if len(posted) != 2 || posted[1].Id != "s1" {
+        t.Fatalf("extend: posted %+v, want the silence s1 extended", posted)
+    }
+    if client.silenceEnds <= issue.SilenceEnds {
+        t.Errorf("extend: stored end %d, want later than %d", client.silenceEnds, issue.SilenceEnds)
+    }
+
+    // Expire
+    issue.StatusId = "5"
+    api.syncAmSilence(cfg, issue)
+    if last := requests[len(requests)-1]; last != "DELETE /api/v2/silence/s1" {
+        t.Errorf("expire: last request %q", last)
+    }
+    if client.silenceId != "" || client.silenceEnds != 0 {
+        t.Errorf("expire: stored silence %q ending %d, want cleared", client.silenceId, client.silenceEnds)
+    }
+    if last := client.events[len(client.events)-1]; last.Event != config.EventAmExpired {
+        t.Errorf("expire: last event %+v", last)
+    }
+
+    // Issues without labels are skipped
+    count := len(requests)
+    api.syncAmSilence(cfg, config.Issue{ GroupId: "g2", IssueKey: "TEST-2", Receiver: "jira", StatusId: "3", StatusName: "In Progress" })
+    if len(requests) != count {
+        t.Errorf("no labels: requests %v", requests[count:])
+    }
+}
+
+func TestAmRequestBasicAuth(t *testing.T) {
+    am := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        user, password, ok := r.BasicAuth()
+        if !ok || user != "am" || password != "pass" || strings.HasPrefix(r.Header.Get("Authorization"), "Bearer") {
+            w.WriteHeader(401)
+        }
+    }))
+    defer am.Close()
+
+    ams := &config.AlertmanagerSilence{ Url: am.URL, Timeout: time.Second, User: "am", Password: "pass" }
+    if err := amRequest(ams, "DELETE", "/api/v2/silence/s1", nil, nil); err != nil {
+        t.Errorf("amRequest() with basic auth: %v", err)
+    }
+    ams.Password = "wrong"
+    if err := amRequest(ams, "DELETE", "/api/v2/silence/s1", nil, nil); err == nil {
+        t.Error("amRequest() with a wrong password: no error")
+    }
+}
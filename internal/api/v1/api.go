@@ -6,7 +6,9 @@ import (
     "io"
     "fmt"
     "time"
+    "sync"
     "net/url"
+    "strconv"
     "io/ioutil"
     "crypto/sha1"
     "encoding/hex"
@@ -19,18 +21,46 @@ import (
 
 var (
     req_chan = make(map[string](chan map[string]interface{}))
+    req_mtx  sync.RWMutex
 )
 
 type Api struct {
     Client       db.DbClient
     Config       *config.Config
     Template     *template.Template
+    templates    map[string]*template.Template
+    ready        readiness
+    limits       *limiter
+    storms       *stormDetector
+    silenced     *silenceCounter
+    silences     *silenceCache
+    flaps        *flapDetector
+    pulls        *pullSources
+    recorded     *eventRecorder
+    mtx          sync.RWMutex
+    dryRun       bool
+}
+
+// getChannel returns the queue of the receiver
+func getChannel(name string) (chan map[string]interface{}, bool) {
+    req_mtx.RLock()
+    defer req_mtx.RUnlock()
+    ch, ok := req_chan[name]
+    return ch, ok
+}
+
+// state returns the configuration and the receiver templates currently in use
+func (api *Api) state() (*config.Config, map[string]*template.Template) {
+    api.mtx.RLock()
+    defer api.mtx.RUnlock()
+    return api.Config, api.templates
 }
 
 type Resp struct {
     Status       string                       `json:"status"`
     Error        string                       `json:"error,omitempty"`
     Warnings     []string                     `json:"warnings,omitempty"`
+    Data         interface{}                  `json:"data,omitempty"`
 }
 
 type Data struct {
@@ -38,13 +68,17 @@ type Data struct {
     Alerts       []map[string]interface{}     `json:"alerts"`
 }
 
-func createIssue(base string, tp jira.BasicAuthTransport, is *jira.Issue) (*jira.Issue, error) {
+func createIssue(base string, tp jira.BasicAuthTransport, is *jira.Issue, adf map[string]interface{}) (*jira.Issue, error) {
 
     jiraClient, err := jira.NewClient(tp.Client(), base)
     if err != nil {
         return is, err
     }
 
+    if adf != nil {
+        return createIssueV3(jiraClient, is, adf)
+    }
+
     is, _, err = jiraClient.Issue.Create(is)
     if err != nil {
         return is, err
@@ -53,6 +87,28 @@ func createIssue(base string, tp jira.BasicAuthTransport, is *jira.Issue) (*jira
     return is, nil
 }
 
+// createIssueV3 creates the issue through the v3 API, which expects the description
+// as an Atlassian Document Format object
+func createIssueV3(jiraClient *jira.Client, is *jira.Issue, adf map[string]interface{}) (*jira.Issue, error) {
+    payload, err := issuePayload(is, adf)
+    if err != nil {
+        return is, err
+    }
+
+    req, err := jiraClient.NewRequest("POST", "rest/api/3/issue", payload)
+    if err != nil {
+        return is, err
+    }
+
+    created := new(jira.Issue)
+    resp, err := jiraClient.Do(req, created)
+    if err != nil {
+        return is, jira.NewJiraError(resp, err)
+    }
+
+    return created, nil
+}
+
 func getHash(text string) string {
     h := sha1.New()
     io.WriteString(h, text)
@@ -67,11 +123,163 @@ func encodeResp(resp *Resp) []byte {
     return jsn
 }
 
+func parseTime(value string) (int64, error) {
+    if value == "" {
+        return 0, nil
+    }
+    if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+        return i, nil
+    }
+    t, err := time.Parse(time.RFC3339, value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid time %q", value)
+    }
+    return t.UTC().Unix(), nil
+}
+
+// Number of events returned by /api/v1/events without and with a limit parameter
+const (
+    defaultEventLimit = 1000
+    maxEventLimit     = 10000
+)
+
+// parseCount parses a non-negative number, an empty value returns the default
+func parseCount(value string, def int) (int, error) {
+    if value == "" {
+        return def, nil
+    }
+    n, err := strconv.Atoi(value)
+    if err != nil || n < 0 {
+        return 0, fmt.Errorf("invalid number %q", value)
+    }
+    return n, nil
+}
+
+func (api *Api) saveEvent(event, group_id, issue_key, details string, alert map[string]interface{}) {
+    ev := config.Event{
+        GroupId:    group_id,
+        IssueKey:   issue_key,
+        Event:      event,
+        Details:    details,
+    }
+    if alert != nil {
+        if fp, ok := alert["fingerprint"].(string); ok {
+            ev.Fingerprint = fp
+        }
+    }
+    if err := api.Client.SaveEvent(ev); err != nil {
+        log.Printf("[error] save event %v", err)
+    }
+}
+
+// eventRecorder keeps the events already recorded for every alert group, so re-sent alerts
+// are recorded once until the group resolves or its issue is purged
+type eventRecorder struct {
+    sync.Mutex
+    groups       map[string]map[string]bool
+}
+
+func newEventRecorder() *eventRecorder {
+    return &eventRecorder{ groups: make(map[string]map[string]bool) }
+}
+
+// first returns whether the event was not recorded for the group yet and marks it recorded
+func (r *eventRecorder) first(group_id, event string) bool {
+    r.Lock()
+    defer r.Unlock()
+    events, ok := r.groups[group_id]
+    if !ok {
+        events = make(map[string]bool)
+        r.groups[group_id] = events
+    }
+    if events[event] {
+        return false
+    }
+    events[event] = true
+    return true
+}
+
+func (r *eventRecorder) forget(group_id string) {
+    r.Lock()
+    defer r.Unlock()
+    delete(r.groups, group_id)
+}
+
 func (api *Api) ApiHealthy(w http.ResponseWriter, r *http.Request) {
     w.WriteHeader(200)
     w.Write(encodeResp(&Resp{Status:"success"}))
 }
 
+func (api *Api) isResolveState(status_id string) bool {
+    cfg, _ := api.state()
+    for _, s := range cfg.Defaults.ResolveState {
+        if status_id == s {
+            return true
+        }
+    }
+    return false
+}
+
+// syncIssue refreshes the stored status of the issue from Jira
+func (api *Api) syncIssue(i config.Issue) (config.Issue, error) {
+    status, err := api.getStatus(i)
+    if err != nil {
+        return i, err
+    }
+    return api.updateIssueStatus(i, status)
+}
+
+// getStatus returns the current status of the issue in Jira
+func (api *Api) getStatus(i config.Issue) (*jira.Status, error) {
+    u, err := url.Parse(i.IssueSelf)
+    if err != nil {
+        return nil, err
+    }
+
+    cfg, _ := api.state()
+    tp := jira.BasicAuthTransport{
+        Username: cfg.Defaults.User,
+        Password: cfg.Defaults.Password,
+    }
+
+    base := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+    jiraClient, err := jira.NewClient(tp.Client(), base)
+    if err != nil {
+        return nil, err
+    }
+
+    issue, _, err := jiraClient.Issue.Get(i.IssueKey, nil)
+    if err != nil {
+        return nil, err
+    }
+    if issue.Fields == nil || issue.Fields.Status == nil {
+        return nil, fmt.Errorf("issue %s has no status", i.IssueKey)
+    }
+    return issue.Fields.Status, nil
+}
+
+// updateIssueStatus stores the status of the issue when it changed in Jira
+func (api *Api) updateIssueStatus(i config.Issue, status *jira.Status) (config.Issue, error) {
+    if i.StatusId != status.ID {
+        if err := api.Client.UpdateStatus(i.GroupId, status.ID, status.Name); err != nil {
+            return i, err
+        }
+        log.Printf("[info] issue status updated: %s", i.IssueKey)
+
+        details := fmt.Sprintf("%s -> %s", i.StatusName, status.Name)
+        api.saveEvent(config.EventStatusChanged, i.GroupId, i.IssueKey, details, nil)
+        if api.isResolveState(i.StatusId) && !api.isResolveState(status.ID) {
+            api.saveEvent(config.EventReopened, i.GroupId, i.IssueKey, details, nil)
+        }
+
+        i.StatusId = status.ID
+        i.StatusName = status.Name
+        i.Updated = time.Now().UTC().Unix()
+    }
+
+    return i, nil
+}
+
 func (api *Api) UpdateStatus() error {
     // Geting issues from database
     issues, err := api.Client.LoadIssues()
@@ -79,54 +287,78 @@ func (api *Api) UpdateStatus() error {
         return err
     }
 
-    tp := jira.BasicAuthTransport{
-        Username: api.Config.Defaults.User,
-        Password: api.Config.Defaults.Password,
-    }
+    cfg, _ := api.state()
+
+    // Alert groups stored with an umbrella issue share its escalation state and its status,
+    // which is requested from Jira once per issue
+    escalated := make(map[string]int)
+    statuses := make(map[string]*jira.Status)
+    failed := make(map[string]bool)
 
     for _, i := range issues {
 
-        u, err := url.Parse(i.IssueSelf)
-        if err != nil {
-            log.Printf("[error] %v", err)
+        if failed[i.IssueKey] {
             continue
         }
+        status, ok := statuses[i.IssueKey]
+        if !ok {
+            status, err = api.getStatus(i)
+            if err != nil {
+                log.Printf("[error] %v", err)
+                failed[i.IssueKey] = true
+                continue
+            }
+            statuses[i.IssueKey] = status
+        }
 
-        base := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
-        jiraClient, err := jira.NewClient(tp.Client(), base)
+        synced, err := api.updateIssueStatus(i, status)
         if err != nil {
             log.Printf("[error] %v", err)
             continue
         }
-        
-        issue, _, err := jiraClient.Issue.Get(i.IssueKey, nil)
+
+        if step, ok := escalated[synced.IssueKey]; ok {
+            synced.Escalation = step
+        }
+        step, err := api.escalate(cfg, synced)
         if err != nil {
             log.Printf("[error] %v", err)
-            continue
         }
+        escalated[synced.IssueKey] = step
 
-        if i.StatusId != issue.Fields.Status.ID {
-            if err := api.Client.UpdateStatus(i.GroupId, issue.Fields.Status.ID, issue.Fields.Status.Name); err != nil {
-                log.Printf("[error] %v", err)
-                continue
-            }
-            log.Printf("[info] issue status updated: %s", i.IssueKey)
-        }
+        api.syncAmSilence(cfg, synced)
 
         if i.Updated + 600 < time.Now().UTC().Unix() {
-            for _, s := range api.Config.Defaults.ResolveState {
-                if i.StatusId == s {
-                    if err := api.Client.DeleteIssue(i.GroupId); err != nil {
-                        log.Printf("[error] %v", err)
-                        continue
-                    }
-                    log.Printf("[info] issue removed from database: %s", i.IssueKey)
+            if api.isResolveState(i.StatusId) {
+                if err := api.Client.DeleteIssue(i.GroupId); err != nil {
+                    log.Printf("[error] %v", err)
+                    continue
                 }
+                log.Printf("[info] issue removed from database: %s", i.IssueKey)
+                api.saveEvent(config.EventPurged, i.GroupId, i.IssueKey, "", nil)
+                api.recorded.forget(i.GroupId)
             }
         }
 
     }
 
+    // Events and dry runs are kept for the event_retention of the db section
+    if cfg.DB.EventRetention > 0 {
+        before := time.Now().Add(-cfg.DB.EventRetention).UTC().Unix()
+        if n, err := api.Client.DeleteEvents(before); err != nil {
+            log.Printf("[error] delete events %v", err)
+        } else if n > 0 {
+            log.Printf("[info] %d events older than %s removed from database", n, cfg.DB.EventRetention)
+        }
+        if n, err := api.Client.DeleteDryRuns(before); err != nil {
+            log.Printf("[error] delete dry runs %v", err)
+        } else if n > 0 {
+            log.Printf("[info] %d dry runs older than %s removed from database", n, cfg.DB.EventRetention)
+        }
+    }
+
+    api.ready.setUpdated(time.Now())
+
     return nil
 }
 
@@ -148,9 +380,18 @@ func (api *Api) ApiAlerts(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+    cfg, _ := api.state()
+    ch, ok := getChannel(data.Receiver)
+    if !ok || cfg.GetReceiver(data.Receiver) == nil {
+        log.Printf("[error] unknown receiver %q - %s", data.Receiver, r.URL.Path)
+        w.WriteHeader(400)
+        w.Write(encodeResp(&Resp{Status:"error", Error:fmt.Sprintf("unknown receiver %q", data.Receiver)}))
+        return
+    }
+
     for _, alert := range data.Alerts {
         go func(alert map[string]interface{}){
-            req_chan[data.Receiver] <- alert 
+            ch <- alert 
         }(alert)
     }
     
@@ -158,112 +399,302 @@ func (api *Api) ApiAlerts(w http.ResponseWriter, r *http.Request) {
     return
 }
 
-func (api *Api) readChannel(receiver *config.Receiver) {
+func (api *Api) ApiEvents(w http.ResponseWriter, r *http.Request) {
+    query := r.URL.Query()
+
+    from, err := parseTime(query.Get("from"))
+    if err != nil {
+        w.WriteHeader(400)
+        w.Write(encodeResp(&Resp{Status:"error", Error:err.Error()}))
+        return
+    }
+    to, err := parseTime(query.Get("to"))
+    if err != nil {
+        w.WriteHeader(400)
+        w.Write(encodeResp(&Resp{Status:"error", Error:err.Error()}))
+        return
+    }
+
+    limit, err := parseCount(query.Get("limit"), defaultEventLimit)
+    if err != nil || limit > maxEventLimit {
+        w.WriteHeader(400)
+        w.Write(encodeResp(&Resp{Status:"error", Error:fmt.Sprintf("invalid limit %q, must be between 1 and %d", query.Get("limit"), maxEventLimit)}))
+        return
+    }
+    offset, err := parseCount(query.Get("offset"), 0)
+    if err != nil {
+        w.WriteHeader(400)
+        w.Write(encodeResp(&Resp{Status:"error", Error:fmt.Sprintf("invalid offset %q", query.Get("offset"))}))
+        return
+    }
+
+    filter := config.EventFilter{
+        GroupId:    query.Get("group_id"),
+        IssueKey:   query.Get("issue_key"),
+        From:       from,
+        To:         to,
+        Limit:      limit,
+        Offset:     offset,
+    }
+
+    events, err := api.Client.LoadEvents(filter)
+    if err != nil {
+        log.Printf("[error] %v - %s", err, r.URL.Path)
+        w.WriteHeader(500)
+        w.Write(encodeResp(&Resp{Status:"error", Error:err.Error()}))
+        return
+    }
+    if events == nil {
+        events = []config.Event{}
+    }
+
+    w.Write(encodeResp(&Resp{Status:"success", Data:events}))
+}
+
+// processAlert creates the Jira issue of a firing alert that has no issue yet,
+// it returns false when the alert was throttled by the rate limits
+func (api *Api) processAlert(cfg *config.Config, templates map[string]*template.Template, receiver *config.Receiver, alert map[string]interface{}) bool {
+    labels, err := json.Marshal(alert["labels"])
+    if err != nil {
+        log.Printf("[error] read alert %v", err)
+        return true
+    }
+
+    group_id := getHash(string(labels))
+
+    task, found, err := api.Client.LoadIssue(group_id)
+    if err != nil {
+        log.Printf("[error] load issue %v", err)
+        return true
+    }
+
+    if alert["status"] == "resolved" {
+        api.limits.forget(receiver.Name, group_id)
+        api.recorded.forget(group_id)
+        api.flaps.resolved(group_id, time.Now())
+        if found {
+            api.saveEvent(config.EventResolved, group_id, task.IssueKey, "", alert)
+        }
+        return true
+    }
+
+    // Alerts matching an active silence do not create issues
+    sl, silenced, err := api.silencedBy(alert)
+    if err != nil {
+        log.Printf("[error] load silences %v", err)
+        return true
+    }
+    // Alerts re-sent by Alertmanager are recorded once per group
+    if silenced {
+        api.limits.forget(receiver.Name, group_id)
+        api.silenced.add(sl.Id, receiver.Name)
+        details := fmt.Sprintf("silence %d", sl.Id)
+        if api.recorded.first(group_id, config.EventSilenced + details) {
+            api.saveEvent(config.EventSilenced, group_id, task.IssueKey, details, alert)
+        }
+        return true
+    }
+
+    if found {
+        api.limits.forget(receiver.Name, group_id)
+        if api.recorded.first(group_id, config.EventDeduplicated + task.IssueKey) {
+            api.saveEvent(config.EventDeduplicated, group_id, task.IssueKey, "", alert)
+        }
+        // Alerts that start flapping after their issue was created tag the issue
+        if transitions, flapping := api.flaps.observe(receiver, group_id, time.Now()); flapping {
+            tp := jira.BasicAuthTransport{
+                Username: cfg.Defaults.User,
+                Password: cfg.Defaults.Password,
+            }
+            if err := addLabel(task.IssueSelf, tp, task.IssueKey, receiver.Flapping.Label); err != nil {
+                log.Printf("[error] label issue %s: %v", task.IssueKey, err)
+            }
+            api.reportFlapping(tp, receiver, group_id, task.IssueKey, task.IssueSelf, transitions, alert)
+        }
+        return true
+    }
+
+    // Alerts within a mute time interval are deferred until it ends or dropped
+    if name, ok := cfg.MutedBy(receiver, time.Now()); ok {
+        if receiver.MuteAction == config.MuteDrop {
+            log.Printf("[info] alert dropped within mute time interval %s of receiver %s", name, receiver.Name)
+            api.saveEvent(config.EventMuted, group_id, "", name + ", " + receiver.MuteAction, alert)
+        } else if api.limits.deferAlert(receiver.Name, group_id, alert) {
+            api.saveEvent(config.EventMuted, group_id, "", name + ", " + receiver.MuteAction, alert)
+        }
+        return true
+    }
+
+    // New alerts are held back until they stay firing for the min_firing_duration
+    if hold, added := api.flaps.firing(receiver, group_id, alert, time.Now()); hold {
+        if added {
+            log.Printf("[info] alert held until firing for %s, receiver %s", receiver.Flapping.MinFiringDuration, receiver.Name)
+        }
+        return true
+    }
+
+    // Alerts already recorded in dry run mode are deduplicated like created issues
+    dryRun := api.isDryRun(receiver)
+    if dryRun {
+        runs, err := api.Client.LoadDryRuns(config.DryRunFilter{ GroupId: group_id })
+        if err != nil {
+            log.Printf("[error] load dry runs %v", err)
+            return true
+        }
+        if len(runs) > 0 {
+            return true
+        }
+    }
+
+    // During an alert storm the new alerts are reported in the umbrella issue
+    if api.storms.observe(receiver, group_id, time.Now()) {
+        if !api.storms.hold(receiver.Name, group_id, alert) {
+            api.limits.Lock()
+            api.limits.countDropped(receiver.Name, group_id, "held in the storm")
+            api.limits.Unlock()
+        }
+        return true
+    }
+
+    is, issueADF, err := RenderIssue(receiver, templates[receiver.Name], alert)
+    if err != nil {
+        log.Printf("[error] render issue for receiver %s: %v, using fallback summary", receiver.Name, err)
+        is, issueADF = FallbackIssue(receiver, templates[receiver.Name], alert, err)
+    }
+
+    // Flapping alerts get one issue tagged with the flapping label
+    transitions, flapping := api.flaps.flapping(receiver, group_id, time.Now())
+    if flapping {
+        is.Fields.Labels = append(is.Fields.Labels, receiver.Flapping.Label)
+    }
+
+    tp := jira.BasicAuthTransport{
+        Username: cfg.Defaults.User,
+        Password: cfg.Defaults.Password,
+    }
+
+    issues, err := api.Client.LoadIssues()
+    if err != nil {
+        log.Printf("[error] load issues %v", err)
+        return true
+    }
+
+    if scope, limit, ok := api.allow(cfg, receiver, issues); !ok {
+        overflow, added := api.limits.overflow(receiver, scope, group_id, alert)
+        if added {
+            log.Printf("[warn] rate limit %s exceeded for receiver %s, alert overflow %s", limit, receiver.Name, overflow)
+            api.saveEvent(config.EventThrottled, group_id, "", limit + ", " + overflow, alert)
+        }
+        return false
+    }
+    api.limits.forget(receiver.Name, group_id)
+
+    // Alerts with the same link key are sub-tasks of one parent issue in parent mode
+    link_key := linkKey(receiver, alert)
+    if receiver.ParentMode != nil && link_key != "" {
+        parent, err := api.parentIssue(tp, receiver, templates[receiver.Name], link_key, alert, dryRun)
+        if err != nil {
+            log.Printf("[error] parent issue for receiver %s: %v, creating the issue without parent", receiver.Name, err)
+        } else if parent != "" {
+            // jira.Parent is sent with an empty id, which Jira does not accept
+            is.Fields.Type = receiver.ParentMode.SubtaskType
+            if is.Fields.Unknowns == nil {
+                is.Fields.Unknowns = map[string]interface{}{}
+            }
+            is.Fields.Unknowns["parent"] = map[string]string{"key": parent}
+        }
+    }
+
+    if dryRun {
+        if err := api.saveDryRun(receiver, group_id, is, issueADF, alert); err != nil {
+            log.Printf("[error] save dry run %v", err)
+        }
+        return true
+    }
+
+    is, err = createIssue(receiver.ApiUrl, tp, is, issueADF)
+    if err != nil {
+        log.Printf("[error] create issue %v", err)
+        return true
+    }
+
+    tk := config.Issue{
+        GroupId:    group_id,
+        Receiver:   receiver.Name,
+        Template:   templates[receiver.Name].Name(),
+        IssueId:    is.ID,
+        IssueKey:   is.Key,
+        IssueSelf:  is.Self,
+        LinkKey:    link_key,
+        Labels:     alertLabels(alert),
+    }
+    if err := api.Client.SaveIssue(tk); err != nil {
+        log.Printf("[error] save issue %v", err)
+        return true
+    }
+
+    log.Printf("[info] create issue: %s", is.Key)
+    api.saveEvent(config.EventCreated, group_id, is.Key, receiver.Name, alert)
+
+    if flapping {
+        api.reportFlapping(tp, receiver, group_id, is.Key, is.Self, transitions, alert)
+    }
+
+    api.attachFiles(tp, receiver, templates[receiver.Name], tk, alert)
+
+    if receiver.ParentMode == nil && link_key != "" {
+        api.linkIssue(tp, receiver, issues, tk)
+    }
+    return true
+}
+
+func (api *Api) readChannel(name string, ch chan map[string]interface{}) {
     for {
 
-        for i := 0; i < len(req_chan[receiver.Name]); i++ {
-            select {
-                case alert := <- req_chan[receiver.Name]:
-
-                    labels, err := json.Marshal(alert["labels"])
-                    if err != nil {
-                        log.Printf("[error] read alert %v", err)
-                        continue
-                    }
-
-                    if alert["status"] == "resolved" {
-                        continue
-                    }
-
-                    group_id := getHash(string(labels))
-
-                    task, err := api.Client.LoadIssue(group_id)
-                    if err != nil {
-                        log.Printf("[error] %v", err)
-                        continue
-                    }
-
-                    if task.GroupId != "" {
-                        continue
-                    }
-
-                    issueSummary, err := api.Template.Execute(receiver.Summary, alert)
-                    if err != nil {
-                        log.Printf("[error] %v", err)
-                        continue
-                    }
-
-                    issueDesc, err := api.Template.Execute(receiver.Description, alert)
-                    if err != nil {
-                        log.Printf("[error] %v", err)
-                        continue
-                    }
-
-                    is := &jira.Issue{
-                        Fields: &jira.IssueFields{
-                            Project:     receiver.Project,
-                            Type:        receiver.IssueType,
-                            Summary:     issueSummary,
-                            Description: issueDesc,
-                        },
-                    }
-
-                    if len(receiver.Components) > 0 {
-                        for _, component := range receiver.Components {
-                            is.Fields.Components = append(is.Fields.Components, &component)
-                        }
-                    }
-
-                    if len(receiver.Fields) > 0 {
-                        //for key, value := range receiver.Fields {
-                        //    is.Fields.Unknowns[key] = value
-                        //}
-                    }
-
-                    tp := jira.BasicAuthTransport{
-                        Username: api.Config.Defaults.User,
-                        Password: api.Config.Defaults.Password,
-                    }
-
-                    issues, err := api.Client.LoadIssues()
-                    if err != nil {
-                        log.Printf("[error] load issues %v", err)
-                        continue
-                    }
-                    if len(issues) > api.Config.DB.CreationLimit {
-                        log.Print("[error] exceeded the limit for creating tasks")
-                        continue
-                    }
-
-                    is, err = createIssue(receiver.ApiUrl, tp, is)
-                    if err != nil {
-                        log.Printf("[error] create issue %v", err)
-                        continue
-                    }
-
-                    tk := config.Issue{
-                        GroupId:    group_id,
-                        IssueId:    is.ID,
-                        IssueKey:   is.Key,
-                        IssueSelf:  is.Self,
-                    }
-                    if err := api.Client.SaveIssue(tk); err != nil {
-                        log.Printf("[error] save issue %v", err)
-                        continue
-                    }
-
-                    log.Printf("[info] create issue: %s", is.Key)
-                    
+        // The receiver settings are looked up on every pass, so a reload applies to queued alerts
+        cfg, templates := api.state()
+        receiver := cfg.GetReceiver(name)
+        if receiver == nil {
+            time.Sleep(5 * time.Second)
+            continue
+        }
 
+        // Alerts deferred by a mute time interval are released when it ends
+        if _, muted := cfg.MutedBy(receiver, time.Now()); !muted {
+            for _, pa := range api.limits.takeDeferred(name) {
+                api.processAlert(cfg, templates, receiver, pa.alert)
+            }
+        }
+
+        // Alerts held by the flapping detection are released once they stayed firing
+        for _, pa := range api.flaps.due(receiver, time.Now()) {
+            api.processAlert(cfg, templates, receiver, pa.alert)
+        }
+
+        // Alerts queued by the rate limits are retried first, until the limits are exhausted again
+        for _, pa := range api.limits.getQueued(name) {
+            if !api.processAlert(cfg, templates, receiver, pa.alert) {
+                break
+            }
+        }
+
+        for i := 0; i < len(ch); i++ {
+            select {
+                case alert := <- ch:
+                    api.processAlert(cfg, templates, receiver, alert)
                 default:
                     continue
             }
         }
 
+        api.flushAggregated(cfg, templates, receiver)
+        api.flushStorm(cfg, templates, receiver)
+
         time.Sleep(5 * time.Second)
     }
 }
 
+
 func New(config *config.Config) (*Api, error) {
     // Connection to data base
     client, err := db.NewClient(config.DB)
@@ -277,16 +708,65 @@ func New(config *config.Config) (*Api, error) {
         return nil, err
     }
 
-    tmpl, err := template.LoadTemplate(config.Template)
+    tmpl, templates, err := config.LoadTemplates()
     if err != nil {
         return nil, err
     }
 
-    api := &Api{ Client: client, Config: config, Template: tmpl }
-    for _, receiver := range api.Config.Receivers {
-        req_chan[receiver.Name] = make(chan map[string]interface{}, 1000)
-        go api.readChannel(receiver)
-    }
+    api := &Api{ Client: client, Config: config, Template: tmpl, templates: templates, limits: newLimiter(), storms: newStormDetector(), silenced: newSilenceCounter(), silences: newSilenceCache(), flaps: newFlapDetector(), pulls: newPullSources(), recorded: newEventRecorder() }
+    api.startReceivers()
+    api.startSources()
     
     return api, nil
+}
+
+// startReceivers creates the queues of the configured receivers that are not running yet
+func (api *Api) startReceivers() {
+    cfg, _ := api.state()
+
+    req_mtx.Lock()
+    defer req_mtx.Unlock()
+    for _, receiver := range cfg.Receivers {
+        if _, ok := req_chan[receiver.Name]; ok {
+            continue
+        }
+        ch := make(chan map[string]interface{}, 1000)
+        req_chan[receiver.Name] = ch
+        go api.readChannel(receiver.Name, ch)
+    }
+}
+
+// ApiReload returns the handler of /-/reload, which reloads the configuration with the given function
+func ApiReload(reload func() error) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != "POST" {
+            writeError(w, 405, fmt.Errorf("method %s not allowed", r.Method))
+            return
+        }
+        if err := reload(); err != nil {
+            writeError(w, 500, err)
+            return
+        }
+        w.Write(encodeResp(&Resp{Status:"success"}))
+    }
+}
+
+// Reload replaces the configuration and templates, the database and web settings
+// are only applied on restart
+func (api *Api) Reload(cfg *config.Config) error {
+    tmpl, templates, err := cfg.LoadTemplates()
+    if err != nil {
+        return err
+    }
+
+    api.mtx.Lock()
+    api.Config = cfg
+    api.Template = tmpl
+    api.templates = templates
+    api.mtx.Unlock()
+
+    api.startReceivers()
+    api.startSources()
+
+    return nil
 }
\ No newline at end of file
@@ -0,0 +1,61 @@
+package v1
+
+import (
+    "errors"
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "github.com/ltkh/jiramanager/internal/db"
+    "github.com/ltkh/jiramanager/internal/config"
+    "github.com/ltkh/jiramanager/internal/template"
+)
+
+// fakeDb fails LoadIssue, the other methods of the interface are not expected to be called
+type fakeDb struct {
+    db.DbClient
+    loadErr      error
+    events       []config.Event
+}
+
+func (f *fakeDb) LoadIssue(group_id string) (config.Issue, bool, error) {
+    return config.Issue{}, false, f.loadErr
+}
+
+func (f *fakeDb) SaveEvent(event config.Event) error {
+    f.events = append(f.events, event)
+    return nil
+}
+
+func TestProcessAlertLoadIssueError(t *testing.T) {
+    requests := 0
+    jira := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests++
+        w.WriteHeader(500)
+    }))
+    defer jira.Close()
+
+    receiver := &config.Receiver{ Name: "jira", ApiUrl: jira.URL, Summary: "{{ .labels.alertname }}" }
+    cfg := &config.Config{
+        Defaults:   &config.Defaults{},
+        DB:         &config.DB{},
+        Receivers:  []*config.Receiver{receiver},
+    }
+    client := &fakeDb{ loadErr: errors.New("database is locked") }
+    api := &Api{ Client: client, Config: cfg, limits: newLimiter(), storms: newStormDetector(), silenced: newSilenceCounter(), silences: newSilenceCache(), flaps: newFlapDetector(), recorded: newEventRecorder() }
+
+    alert := map[string]interface{}{
+        "status": "firing",
+        "labels": map[string]interface{}{ "alertname": "up" },
+    }
+    templates := map[string]*template.Template{ "jira": &template.Template{} }
+
+    if !api.processAlert(cfg, templates, receiver, alert) {
+        t.Error("processAlert() = false, the alert must not be throttled")
+    }
+    if requests > 0 {
+        t.Errorf("processAlert() sent %d requests to Jira after a database error", requests)
+    }
+    if len(client.events) > 0 {
+        t.Errorf("processAlert() recorded events %+v after a database error", client.events)
+    }
+}
@@ -0,0 +1,200 @@
+package v1
+
+import (
+    "io"
+    "fmt"
+    "log"
+    "net"
+    "mime"
+    "path"
+    "bytes"
+    "strings"
+    "syscall"
+    "net/url"
+    "net/http"
+    "io/ioutil"
+    "encoding/json"
+    "github.com/andygrunwald/go-jira"
+    "github.com/ltkh/jiramanager/internal/config"
+    "github.com/ltkh/jiramanager/internal/template"
+)
+
+// matchContentType reports whether the media type matches one of the patterns like image/png or image/*
+func matchContentType(mediaType string, patterns []string) bool {
+    for _, p := range patterns {
+        if p == mediaType || p == "*/*" {
+            return true
+        }
+        if strings.HasSuffix(p, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(p, "*")) {
+            return true
+        }
+    }
+    return false
+}
+
+// allowedUrl reports whether the url has the http or https scheme and matches one of the allowed
+// hosts or url prefixes, any host is allowed when the list is empty
+func allowedUrl(u *url.URL, allowed []string) error {
+    if u.Scheme != "http" && u.Scheme != "https" {
+        return fmt.Errorf("unsupported url scheme %q", u.Scheme)
+    }
+    if len(allowed) == 0 {
+        return nil
+    }
+    for _, a := range allowed {
+        if !strings.Contains(a, "://") {
+            if strings.EqualFold(u.Hostname(), a) {
+                return nil
+            }
+            continue
+        }
+        // A prefix matches the scheme and host exactly, so example.com does not match example.com.evil
+        prefix, err := url.Parse(a)
+        if err == nil && prefix.Scheme == u.Scheme && strings.EqualFold(prefix.Host, u.Host) && strings.HasPrefix(u.Path, prefix.Path) {
+            return nil
+        }
+    }
+    return fmt.Errorf("url is not in allowed_urls")
+}
+
+// checkAddress rejects connections to loopback, link-local, private and unspecified addresses,
+// which are only reached when the hosts are listed in allowed_urls
+func checkAddress(network, address string, c syscall.RawConn) error {
+    host, _, err := net.SplitHostPort(address)
+    if err != nil {
+        return err
+    }
+    ip := net.ParseIP(host)
+    if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+        return fmt.Errorf("address %s is not allowed without allowed_urls", host)
+    }
+    return nil
+}
+
+// attachmentClient returns the client downloading the attachments, every redirect
+// is checked against allowed_urls
+func attachmentClient(at *config.Attachments) *http.Client {
+    dialer := &net.Dialer{ Timeout: at.Timeout }
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    transport.DialContext = dialer.DialContext
+    // Connections through a proxy would bypass the address check
+    if len(at.AllowedUrls) == 0 {
+        dialer.Control = checkAddress
+        transport.Proxy = nil
+    }
+
+    return &http.Client{
+        Timeout:   at.Timeout,
+        Transport: transport,
+        CheckRedirect: func(req *http.Request, via []*http.Request) error {
+            if len(via) >= 10 {
+                return fmt.Errorf("stopped after 10 redirects")
+            }
+            return allowedUrl(req.URL, at.AllowedUrls)
+        },
+    }
+}
+
+// fetchAttachment downloads a file within the size and content type limits
+// and returns its content and file name
+func fetchAttachment(at *config.Attachments, rawurl string, index int) ([]byte, string, error) {
+    u, err := url.Parse(rawurl)
+    if err != nil {
+        return nil, "", err
+    }
+    if err := allowedUrl(u, at.AllowedUrls); err != nil {
+        return nil, "", err
+    }
+
+    resp, err := attachmentClient(at).Get(u.String())
+    if err != nil {
+        return nil, "", err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != 200 {
+        return nil, "", fmt.Errorf("unexpected status %s", resp.Status)
+    }
+    if resp.ContentLength > at.MaxSize {
+        return nil, "", fmt.Errorf("size %d exceeds max_size %d", resp.ContentLength, at.MaxSize)
+    }
+
+    mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+    if err != nil {
+        return nil, "", fmt.Errorf("invalid content type %q", resp.Header.Get("Content-Type"))
+    }
+    if !matchContentType(mediaType, at.ContentTypes) {
+        return nil, "", fmt.Errorf("content type %s is not allowed", mediaType)
+    }
+
+    content, err := ioutil.ReadAll(io.LimitReader(resp.Body, at.MaxSize + 1))
+    if err != nil {
+        return nil, "", err
+    }
+    if int64(len(content)) > at.MaxSize {
+        return nil, "", fmt.Errorf("size exceeds max_size %d", at.MaxSize)
+    }
+
+    name := path.Base(u.Path)
+    if name == "." || name == "/" || path.Ext(name) == "" {
+        name = fmt.Sprintf("attachment-%d", index)
+        if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+            name = name + exts[0]
+        }
+    }
+
+    return content, name, nil
+}
+
+// attachFiles uploads the alert JSON and the files of the attachment urls to the issue,
+// failures are logged and do not affect the created issue
+func (api *Api) attachFiles(tp jira.BasicAuthTransport, receiver *config.Receiver, tmpl *template.Template, tk config.Issue, alert map[string]interface{}) {
+    at := receiver.Attachments
+    if at == nil {
+        return
+    }
+
+    u, err := url.Parse(tk.IssueSelf)
+    if err != nil {
+        log.Printf("[error] attach files to %s: %v", tk.IssueKey, err)
+        return
+    }
+    jiraClient, err := jira.NewClient(tp.Client(), fmt.Sprintf("%s://%s", u.Scheme, u.Host))
+    if err != nil {
+        log.Printf("[error] attach files to %s: %v", tk.IssueKey, err)
+        return
+    }
+
+    post := func(content []byte, name string) {
+        if _, _, err := jiraClient.Issue.PostAttachment(tk.IssueKey, bytes.NewReader(content), name); err != nil {
+            log.Printf("[error] attach %s to %s: %v", name, tk.IssueKey, err)
+            return
+        }
+        log.Printf("[info] attach %s to %s", name, tk.IssueKey)
+    }
+
+    if at.AlertJson {
+        if content, err := json.MarshalIndent(alert, "", "  "); err == nil {
+            post(content, "alert.json")
+        }
+    }
+
+    for i, text := range at.Urls {
+        rawurl, err := tmpl.Execute(text, alert)
+        if err != nil {
+            log.Printf("[error] attachment url of receiver %s: %v", receiver.Name, err)
+            continue
+        }
+        rawurl = strings.TrimSpace(rawurl)
+        if rawurl == "" || rawurl == "<no value>" {
+            continue
+        }
+
+        content, name, err := fetchAttachment(at, rawurl, i + 1)
+        if err != nil {
+            log.Printf("[error] fetch attachment %s for %s: %v", rawurl, tk.IssueKey, err)
+            continue
+        }
+        post(content, name)
+    }
+}
@@ -0,0 +1,104 @@
+package v1
+
+import (
+    "time"
+    "strings"
+    "testing"
+    "net/url"
+    "net/http"
+    "net/http/httptest"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+func TestAllowedUrl(t *testing.T) {
+    allowed := []string{"grafana.example.com", "https://prometheus.example.com/api/"}
+    tests := []struct {
+        url      string
+        allowed  []string
+        ok       bool
+    }{
+        {"http://any.host/graph.png", nil, true},
+        {"file:///etc/passwd", nil, false},
+        {"https://grafana.example.com/render/d/1", allowed, true},
+        {"http://GRAFANA.example.com:3000/render", allowed, true},
+        {"https://grafana.example.com.evil.com/render", allowed, false},
+        {"https://prometheus.example.com/api/v1/query", allowed, true},
+        {"https://prometheus.example.com/graph", allowed, false},
+        {"http://prometheus.example.com/api/v1/query", allowed, false},
+        {"https://prometheus.example.com.evil.com/api/v1/query", allowed, false},
+    }
+    for _, tt := range tests {
+        u, err := url.Parse(tt.url)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if err := allowedUrl(u, tt.allowed); (err == nil) != tt.ok {
+            t.Errorf("allowedUrl(%s, %v) = %v, want allowed %v", tt.url, tt.allowed, err, tt.ok)
+        }
+    }
+}
+
+func TestFetchAttachmentLocal(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/redirect" {
+            http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", 302)
+            return
+        }
+        w.Header().Set("Content-Type", "image/png")
+        w.Write([]byte("png"))
+    }))
+    defer server.Close()
+    u, _ := url.Parse(server.URL)
+
+    // The test server listens on a loopback address
+    at := &config.Attachments{ MaxSize: 1024, ContentTypes: []string{"image/*"}, Timeout: time.Second }
+    if _, _, err := fetchAttachment(at, server.URL + "/graph.png", 1); err == nil || !strings.Contains(err.Error(), "not allowed") {
+        t.Errorf("fetch from loopback without allowed_urls: got error %v", err)
+    }
+
+    at.AllowedUrls = []string{u.Hostname()}
+    content, name, err := fetchAttachment(at, server.URL + "/graph.png", 1)
+    if err != nil || string(content) != "png" || name != "graph.png" {
+        t.Errorf("fetch from allowed loopback host = %q, %q, %v", content, name, err)
+    }
+
+    if _, _, err := fetchAttachment(at, server.URL + "/redirect", 1); err == nil || !strings.Contains(err.Error(), "allowed_urls") {
+        t.Errorf("redirect to a host not in allowed_urls: got error %v", err)
+    }
+}
+
+func TestCheckAddress(t *testing.T) {
+    tests := []struct {
+        address  string
+        ok       bool
+    }{
+        {"93.184.216.34:80", true},
+        {"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+        {"127.0.0.1:80", false},
+        {"[::1]:80", false},
+        {"169.254.169.254:80", false},
+        {"[fe80::1]:80", false},
+        {"10.0.0.1:80", false},
+        {"172.16.5.4:443", false},
+        {"192.168.1.1:80", false},
+        {"[fd00::1]:80", false},
+        {"0.0.0.0:80", false},
+    }
+    for _, tt := range tests {
+        if err := checkAddress("tcp", tt.address, nil); (err == nil) != tt.ok {
+            t.Errorf("checkAddress(%s) = %v, want allowed %v", tt.address, err, tt.ok)
+        }
+    }
+}
+
+func TestAttachmentClientProxy(t *testing.T) {
+    // The proxy is only used when the hosts are restricted by allowed_urls
+    at := &config.Attachments{ Timeout: time.Second }
+    if attachmentClient(at).Transport.(*http.Transport).Proxy != nil {
+        t.Error("attachment client without allowed_urls uses a proxy")
+    }
+    at.AllowedUrls = []string{"grafana.example.com"}
+    if attachmentClient(at).Transport.(*http.Transport).Proxy == nil {
+        t.Error("attachment client with allowed_urls ignores the proxy environment")
+    }
+}
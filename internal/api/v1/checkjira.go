@@ -0,0 +1,246 @@
+package v1
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "net/url"
+    "github.com/andygrunwald/go-jira"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+// Fields set by jiramanager itself when creating an issue
+var managedFields = map[string]bool{
+    "project":     true,
+    "issuetype":   true,
+    "summary":     true,
+    "description": true,
+    "priority":    true,
+    "components":  true,
+    "labels":      true,
+}
+
+// getCreateMeta returns the create metadata of the projects matching the receiver project
+func getCreateMeta(jiraClient *jira.Client, project jira.Project) (*jira.CreateMetaInfo, error) {
+    query := url.Values{}
+    query.Set("expand", "projects.issuetypes.fields")
+    switch {
+        case project.Key != "":
+            query.Set("projectKeys", project.Key)
+        case project.ID != "":
+            query.Set("projectIds", project.ID)
+    }
+
+    req, err := jiraClient.NewRequest("GET", "rest/api/2/issue/createmeta?" + query.Encode(), nil)
+    if err != nil {
+        return nil, err
+    }
+
+    meta := new(jira.CreateMetaInfo)
+    resp, err := jiraClient.Do(req, meta)
+    if err != nil {
+        return nil, jira.NewJiraError(resp, err)
+    }
+
+    return meta, nil
+}
+
+// allowedValues returns the allowed values of a field from the create metadata
+func allowedValues(fields map[string]interface{}, key string) ([]map[string]interface{}, bool) {
+    field, ok := fields[key].(map[string]interface{})
+    if !ok {
+        return nil, false
+    }
+    var result []map[string]interface{}
+    if values, ok := field["allowedValues"].([]interface{}); ok {
+        for _, v := range values {
+            if m, ok := v.(map[string]interface{}); ok {
+                result = append(result, m)
+            }
+        }
+    }
+    return result, true
+}
+
+func matchValue(values []map[string]interface{}, id, name string) bool {
+    for _, v := range values {
+        if id != "" && fmt.Sprintf("%v", v["id"]) == id {
+            return true
+        }
+        if name != "" && (strings.EqualFold(fmt.Sprintf("%v", v["name"]), name) || strings.EqualFold(fmt.Sprintf("%v", v["value"]), name)) {
+            return true
+        }
+    }
+    return false
+}
+
+func describeValues(values []map[string]interface{}) string {
+    var options []string
+    for _, v := range values {
+        name := v["name"]
+        if name == nil {
+            name = v["value"]
+        }
+        options = append(options, fmt.Sprintf("%v (%v)", v["id"], name))
+    }
+    if len(options) == 0 {
+        return "none"
+    }
+    return strings.Join(options, ", ")
+}
+
+// describe formats the identifying attributes of a configured value
+func describe(id, key, name string) string {
+    switch {
+        case id != "":
+            return fmt.Sprintf("id %q", id)
+        case key != "":
+            return fmt.Sprintf("key %q", key)
+    }
+    return fmt.Sprintf("name %q", name)
+}
+
+func checkReceiver(rc *config.Receiver, meta *jira.CreateMetaInfo) []error {
+    var errs []error
+
+    var project *jira.MetaProject
+    for _, p := range meta.Projects {
+        if (rc.Project.ID != "" && p.Id == rc.Project.ID) || (rc.Project.Key != "" && p.Key == rc.Project.Key) ||
+            (rc.Project.ID == "" && rc.Project.Key == "" && strings.EqualFold(p.Name, rc.Project.Name)) {
+            project = p
+            break
+        }
+    }
+    if project == nil {
+        var options []string
+        for _, p := range meta.Projects {
+            options = append(options, fmt.Sprintf("%s (%s)", p.Key, p.Name))
+        }
+        if len(options) == 0 {
+            options = append(options, "none")
+        }
+        return append(errs, fmt.Errorf("receiver %q: project %s not found or issues cannot be created in it, valid projects: %s",
+            rc.Name, describe(rc.Project.ID, rc.Project.Key, rc.Project.Name), strings.Join(options, ", ")))
+    }
+
+    var issueType *jira.MetaIssueType
+    for _, t := range project.IssueTypes {
+        if (rc.IssueType.ID != "" && t.Id == rc.IssueType.ID) || (rc.IssueType.ID == "" && strings.EqualFold(t.Name, rc.IssueType.Name)) {
+            issueType = t
+            break
+        }
+    }
+    if issueType == nil {
+        var options []string
+        for _, t := range project.IssueTypes {
+            options = append(options, fmt.Sprintf("%s (%s)", t.Id, t.Name))
+        }
+        return append(errs, fmt.Errorf("receiver %q: issue type %s not found in project %s, valid issue types: %s",
+            rc.Name, describe(rc.IssueType.ID, "", rc.IssueType.Name), project.Key, strings.Join(options, ", ")))
+    }
+
+    fields := map[string]interface{}(issueType.Fields)
+
+    if rc.Sends() && (rc.Priority.ID != "" || rc.Priority.Name != "") {
+        values, ok := allowedValues(fields, "priority")
+        if !ok {
+            errs = append(errs, fmt.Errorf("receiver %q: priority cannot be set for issue type %s in project %s", rc.Name, issueType.Name, project.Key))
+        } else if len(values) > 0 && !matchValue(values, rc.Priority.ID, rc.Priority.Name) {
+            errs = append(errs, fmt.Errorf("receiver %q: invalid priority %s, valid priorities: %s", rc.Name, describe(rc.Priority.ID, "", rc.Priority.Name), describeValues(values)))
+        }
+    }
+
+    if len(rc.Components) > 0 {
+        values, ok := allowedValues(fields, "components")
+        if !ok {
+            errs = append(errs, fmt.Errorf("receiver %q: components cannot be set for issue type %s in project %s", rc.Name, issueType.Name, project.Key))
+        } else {
+            for _, c := range rc.Components {
+                if !matchValue(values, c.ID, c.Name) {
+                    errs = append(errs, fmt.Errorf("receiver %q: invalid component %s, valid components: %s", rc.Name, describe(c.ID, "", c.Name), describeValues(values)))
+                }
+            }
+        }
+    }
+
+    for key := range rc.Fields {
+        if !rc.Sends() {
+            break
+        }
+        if _, ok := fields[key]; !ok {
+            var options []string
+            for k := range fields {
+                if !managedFields[k] {
+                    options = append(options, k)
+                }
+            }
+            sort.Strings(options)
+            errs = append(errs, fmt.Errorf("receiver %q: field %s is not available for issue type %s in project %s, available fields: %s",
+                rc.Name, key, issueType.Name, project.Key, strings.Join(options, ", ")))
+        }
+    }
+
+    var required []string
+    for key, value := range fields {
+        field, ok := value.(map[string]interface{})
+        if !ok || managedFields[key] {
+            continue
+        }
+        if req, _ := field["required"].(bool); !req {
+            continue
+        }
+        if def, _ := field["hasDefaultValue"].(bool); def {
+            continue
+        }
+        if _, ok := rc.Fields[key]; !ok || !rc.Sends() {
+            required = append(required, key)
+        }
+    }
+    sort.Strings(required)
+    for _, key := range required {
+        field := fields[key].(map[string]interface{})
+        msg := fmt.Sprintf("receiver %q: required field %s (%v) is not set in fields", rc.Name, key, field["name"])
+        if !rc.Sends() {
+            msg = fmt.Sprintf("receiver %q: required field %s (%v) is only set with send_fields", rc.Name, key, field["name"])
+        }
+        if values, _ := allowedValues(fields, key); len(values) > 0 {
+            msg = msg + ", valid values: " + describeValues(values)
+        }
+        errs = append(errs, fmt.Errorf("%s", msg))
+    }
+
+    return errs
+}
+
+// CheckJira verifies the project, issue type, priority, components and fields of every receiver
+// against the Jira create metadata
+func CheckJira(cfg *config.Config) []error {
+    var errs []error
+
+    tp := jira.BasicAuthTransport{
+        Username: cfg.Defaults.User,
+        Password: cfg.Defaults.Password,
+    }
+
+    cache := make(map[string]*jira.CreateMetaInfo)
+    for _, rc := range cfg.Receivers {
+        key := fmt.Sprintf("%s|%s|%s|%s", rc.ApiUrl, rc.Project.ID, rc.Project.Key, rc.Project.Name)
+
+        meta, ok := cache[key]
+        if !ok {
+            jiraClient, err := jira.NewClient(tp.Client(), rc.ApiUrl)
+            if err == nil {
+                meta, err = getCreateMeta(jiraClient, rc.Project)
+            }
+            if err != nil {
+                errs = append(errs, fmt.Errorf("receiver %q: get create metadata from %s: %v", rc.Name, rc.ApiUrl, err))
+                continue
+            }
+            cache[key] = meta
+        }
+
+        errs = append(errs, checkReceiver(rc, meta)...)
+    }
+
+    return errs
+}
@@ -0,0 +1,97 @@
+package v1
+
+import (
+    "strings"
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "github.com/andygrunwald/go-jira"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+const createMeta = `{"projects":[{"id":"10000","key":"TEST","name":"Test","issuetypes":[
+    {"id":"1","name":"Bug","fields":{
+        "summary":{"required":true,"name":"Summary"},
+        "priority":{"required":false,"name":"Priority","allowedValues":[{"id":"2","name":"High"}]},
+        "customfield_10001":{"required":true,"name":"Team"}
+    }}
+]}]}`
+
+// fakeCreateMeta serves the create metadata of the project TEST to the user jiramanager
+func fakeCreateMeta(t *testing.T) *httptest.Server {
+    return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if user, pass, ok := r.BasicAuth(); !ok || user != "jiramanager" || pass != "secret" {
+            w.WriteHeader(401)
+            w.Write([]byte(`{"errorMessages":["You are not authenticated"]}`))
+            return
+        }
+        if r.URL.Path != "/rest/api/2/issue/createmeta" {
+            t.Errorf("unexpected request %s", r.URL.Path)
+            w.WriteHeader(404)
+            return
+        }
+        if r.URL.Query().Get("projectKeys") != "TEST" {
+            w.Write([]byte(`{"projects":[]}`))
+            return
+        }
+        w.Write([]byte(createMeta))
+    }))
+}
+
+func TestCheckJira(t *testing.T) {
+    server := fakeCreateMeta(t)
+    defer server.Close()
+
+    tests := []struct {
+        name     string
+        password string
+        project  string
+        issue    string
+        fields   map[string]interface{}
+        priority string
+        send     bool
+        errors   []string
+    }{
+        {"valid receiver", "secret", "TEST", "Bug", map[string]interface{}{"customfield_10001": "ops"}, "High", true, nil},
+        {"auth failure", "wrong", "TEST", "Bug", nil, "", true, []string{"get create metadata", "401"}},
+        {"missing project", "secret", "NOPE", "Bug", nil, "", true, []string{`project key "NOPE" not found`, "valid projects: none"}},
+        {"missing issue type", "secret", "TEST", "Task", nil, "", true, []string{`issue type name "Task" not found in project TEST`, "1 (Bug)"}},
+        {"missing required field", "secret", "TEST", "Bug", nil, "", true, []string{"required field customfield_10001 (Team) is not set"}},
+        {"unavailable field", "secret", "TEST", "Bug", map[string]interface{}{"customfield_10001": "ops", "customfield_2": "x"}, "", true, []string{"field customfield_2 is not available", "customfield_10001"}},
+        {"invalid priority", "secret", "TEST", "Bug", map[string]interface{}{"customfield_10001": "ops"}, "Low", true, []string{`invalid priority name "Low"`, "2 (High)"}},
+        {"fields not sent", "secret", "TEST", "Bug", map[string]interface{}{"customfield_10001": "ops", "customfield_2": "x"}, "Low", false, []string{"required field customfield_10001 (Team) is only set with send_fields"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            cfg := &config.Config{
+                Defaults:   &config.Defaults{ User: "jiramanager", Password: tt.password },
+                Receivers:  []*config.Receiver{{
+                    Name:       "jira",
+                    ApiUrl:     server.URL,
+                    Project:    jira.Project{ Key: tt.project },
+                    IssueType:  jira.IssueType{ Name: tt.issue },
+                    Fields:     tt.fields,
+                    Priority:   jira.Priority{ Name: tt.priority },
+                    SendFields: &tt.send,
+                }},
+            }
+
+            errs := CheckJira(cfg)
+            if len(tt.errors) == 0 {
+                if len(errs) > 0 {
+                    t.Errorf("CheckJira() = %v, want no errors", errs)
+                }
+                return
+            }
+            if len(errs) != 1 {
+                t.Fatalf("CheckJira() = %v, want one error", errs)
+            }
+            for _, want := range tt.errors {
+                if !strings.Contains(errs[0].Error(), want) {
+                    t.Errorf("CheckJira() = %q, want it to contain %q", errs[0], want)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,89 @@
+package v1
+
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "encoding/json"
+    "github.com/andygrunwald/go-jira"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+// SetDryRun enables the dry run mode for all receivers
+func (api *Api) SetDryRun(enabled bool) {
+    api.mtx.Lock()
+    defer api.mtx.Unlock()
+    api.dryRun = enabled
+}
+
+// isDryRun reports whether the issues of the receiver are only logged
+func (api *Api) isDryRun(receiver *config.Receiver) bool {
+    api.mtx.RLock()
+    defer api.mtx.RUnlock()
+    return api.dryRun || receiver.DryRun
+}
+
+// saveDryRun logs and stores the payload of the issue the receiver would have created
+func (api *Api) saveDryRun(receiver *config.Receiver, group_id string, is *jira.Issue, adf map[string]interface{}, alert map[string]interface{}) error {
+    payload, err := issuePayload(is, adf)
+    if err != nil {
+        return err
+    }
+    jsn, err := json.Marshal(payload)
+    if err != nil {
+        return err
+    }
+
+    log.Printf("[info] dry run issue for receiver %s: %s", receiver.Name, jsn)
+
+    dr := config.DryRun{
+        GroupId:    group_id,
+        Receiver:   receiver.Name,
+        Payload:    jsn,
+    }
+    if fp, ok := alert["fingerprint"].(string); ok {
+        dr.Fingerprint = fp
+    }
+
+    return api.Client.SaveDryRun(dr)
+}
+
+// ApiDryRuns returns the issues recorded in dry run mode
+func (api *Api) ApiDryRuns(w http.ResponseWriter, r *http.Request) {
+    if r.Method != "GET" {
+        writeError(w, 405, fmt.Errorf("method %s not allowed", r.Method))
+        return
+    }
+
+    query := r.URL.Query()
+
+    from, err := parseTime(query.Get("from"))
+    if err != nil {
+        writeError(w, 400, err)
+        return
+    }
+    to, err := parseTime(query.Get("to"))
+    if err != nil {
+        writeError(w, 400, err)
+        return
+    }
+
+    filter := config.DryRunFilter{
+        GroupId:    query.Get("group_id"),
+        Receiver:   query.Get("receiver"),
+        From:       from,
+        To:         to,
+    }
+
+    runs, err := api.Client.LoadDryRuns(filter)
+    if err != nil {
+        log.Printf("[error] %v - %s", err, r.URL.Path)
+        writeError(w, 500, err)
+        return
+    }
+    if runs == nil {
+        runs = []config.DryRun{}
+    }
+
+    w.Write(encodeResp(&Resp{Status:"success", Data:runs}))
+}
@@ -0,0 +1,169 @@
+package v1
+
+import (
+    "time"
+    "testing"
+    "net/http"
+    "encoding/json"
+    "net/http/httptest"
+    "github.com/ltkh/jiramanager/internal/config"
+    "github.com/ltkh/jiramanager/internal/template"
+)
+
+// dryRunDb stores the dry runs and keeps the last filter they were loaded with
+type dryRunDb struct {
+    fakeDb
+    runs         []config.DryRun
+    filter       config.DryRunFilter
+    purged       []int64
+}
+
+func (f *dryRunDb) LoadIssues() ([]config.Issue, error) {
+    return nil, nil
+}
+
+func (f *dryRunDb) LoadSilences(filter config.SilenceFilter) ([]config.Silence, error) {
+    return nil, nil
+}
+
+func (f *dryRunDb) SaveDryRun(dr config.DryRun) error {
+    f.runs = append(f.runs, dr)
+    return nil
+}
+
+func (f *dryRunDb) LoadDryRuns(filter config.DryRunFilter) ([]config.DryRun, error) {
+    f.filter = filter
+    var result []config.DryRun
+    for _, dr := range f.runs {
+        if filter.GroupId == "" || dr.GroupId == filter.GroupId {
+            result = append(result, dr)
+        }
+    }
+    return result, nil
+}
+
+func (f *dryRunDb) DeleteEvents(before int64) (int64, error) {
+    return 0, nil
+}
+
+func (f *dryRunDb) DeleteDryRuns(before int64) (int64, error) {
+    var kept []config.DryRun
+    for _, dr := range f.runs {
+        if dr.Created >= before {
+            kept = append(kept, dr)
+        }
+    }
+    f.purged = append(f.purged, before)
+    n := len(f.runs) - len(kept)
+    f.runs = kept
+    return int64(n), nil
+}
+
+func TestProcessAlertDryRun(t *testing.T) {
+    requests := 0
+    jira := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests++
+        w.WriteHeader(500)
+    }))
+    defer jira.Close()
+
+    receiver := &config.Receiver{ Name: "jira", ApiUrl: jira.URL, Summary: "{{ .labels.alertname }}", DryRun: true }
+    cfg := &config.Config{
+        Defaults:   &config.Defaults{},
+        Receivers:  []*config.Receiver{receiver},
+    }
+    client := &dryRunDb{}
+    api := &Api{ Client: client, Config: cfg, limits: newLimiter(), storms: newStormDetector(), silences: newSilenceCache(), flaps: newFlapDetector(), recorded: newEventRecorder() }
+    templates := map[string]*template.Template{ "jira": loadTestTemplate(t) }
+
+    // Alerts re-sent by Alertmanager are recorded once per group
+    for _, name := range []string{"up", "up", "down", "up"} {
+        alert := map[string]interface{}{
+            "status":      "firing",
+            "labels":      map[string]interface{}{ "alertname": name },
+            "fingerprint": name,
+        }
+        if !api.processAlert(cfg, templates, receiver, alert) {
+            t.Fatalf("processAlert(%s) = false, the alert must not be throttled", name)
+        }
+    }
+
+    if len(client.runs) != 2 || client.runs[0].Fingerprint != "up" || client.runs[1].Fingerprint != "down" {
+        t.Errorf("dry runs %+v, want one of each group", client.runs)
+    }
+    var payload struct {
+        Fields   struct {
+            Summary  string
+        }
+    }
+    if err := json.Unmarshal(client.runs[0].Payload, &payload); err != nil || payload.Fields.Summary != "up" {
+        t.Errorf("dry run payload %s", client.runs[0].Payload)
+    }
+    if requests > 0 {
+        t.Errorf("%d requests sent to Jira in dry run mode", requests)
+    }
+}
+
+func TestApiDryRuns(t *testing.T) {
+    client := &dryRunDb{ runs: []config.DryRun{
+        { Id: 1, GroupId: "g1", Receiver: "jira", Payload: []byte(`{}`) },
+        { Id: 2, GroupId: "g2", Receiver: "jira", Payload: []byte(`{}`) },
+    }}
+    api := &Api{ Client: client }
+
+    tests := []struct {
+        name     string
+        method   string
+        query    string
+        code     int
+        filter   config.DryRunFilter
+        count    int
+    }{
+        {"all", "GET", "", 200, config.DryRunFilter{}, 2},
+        {"group", "GET", "?group_id=g2", 200, config.DryRunFilter{ GroupId: "g2" }, 1},
+        {"receiver and times", "GET", "?receiver=jira&from=100&to=1970-01-01T00:05:00Z", 200, config.DryRunFilter{ Receiver: "jira", From: 100, To: 300 }, 2},
+        {"invalid from", "GET", "?from=yesterday", 400, config.DryRunFilter{}, 0},
+        {"invalid to", "GET", "?to=tomorrow", 400, config.DryRunFilter{}, 0},
+        {"method not allowed", "POST", "", 405, config.DryRunFilter{}, 0},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            client.filter = config.DryRunFilter{}
+            w := httptest.NewRecorder()
+            api.ApiDryRuns(w, httptest.NewRequest(tt.method, "/api/v1/dry_runs" + tt.query, nil))
+            if w.Code != tt.code {
+                t.Fatalf("ApiDryRuns() = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+            }
+            if client.filter != tt.filter {
+                t.Errorf("filter %+v, want %+v", client.filter, tt.filter)
+            }
+            if tt.code != 200 {
+                return
+            }
+
+            var resp struct {
+                Data     []config.DryRun
+            }
+            if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.Data) != tt.count {
+                t.Errorf("response %s, want %d dry runs", w.Body.String(), tt.count)
+            }
+        })
+    }
+}
+
+func TestUpdateStatusPurgesDryRuns(t *testing.T) {
+    now := time.Now().UTC().Unix()
+    client := &dryRunDb{ runs: []config.DryRun{
+        { Id: 1, GroupId: "g1", Created: now - 3 * 86400 },
+        { Id: 2, GroupId: "g2", Created: now - 3600 },
+    }}
+    cfg := &config.Config{ DB: &config.DB{ EventRetention: 24 * time.Hour } }
+    api := &Api{ Client: client, Config: cfg }
+
+    if err := api.UpdateStatus(); err != nil {
+        t.Fatal(err)
+    }
+    if len(client.purged) != 1 || len(client.runs) != 1 || client.runs[0].Id != 2 {
+        t.Errorf("dry runs %+v after purging before %v, want the dry runs within the event_retention", client.runs, client.purged)
+    }
+}
@@ -0,0 +1,134 @@
+package v1
+
+import (
+    "fmt"
+    "log"
+    "time"
+    "net/url"
+    "strings"
+    "github.com/andygrunwald/go-jira"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+// matchStatus reports whether the issue status id or name is one of the statuses, any status matches an empty list
+func matchStatus(i config.Issue, statuses []string) bool {
+    if len(statuses) == 0 {
+        return true
+    }
+    for _, s := range statuses {
+        if s == i.StatusId || strings.EqualFold(s, i.StatusName) {
+            return true
+        }
+    }
+    return false
+}
+
+// applyEscalation sets the priority and assignee of the step, which can be applied again when
+// a later action of the step fails
+func applyEscalation(tp jira.BasicAuthTransport, i config.Issue, step *config.Escalation) ([]string, error) {
+    u, err := url.Parse(i.IssueSelf)
+    if err != nil {
+        return nil, err
+    }
+
+    jiraClient, err := jira.NewClient(tp.Client(), fmt.Sprintf("%s://%s", u.Scheme, u.Host))
+    if err != nil {
+        return nil, err
+    }
+
+    var actions []string
+
+    if step.Priority.ID != "" || step.Priority.Name != "" {
+        priority := map[string]string{}
+        if step.Priority.ID != "" {
+            priority["id"] = step.Priority.ID
+        } else {
+            priority["name"] = step.Priority.Name
+        }
+        data := map[string]interface{}{
+            "fields": map[string]interface{}{ "priority": priority },
+        }
+        if resp, err := jiraClient.Issue.UpdateIssue(i.IssueKey, data); err != nil {
+            return actions, jira.NewJiraError(resp, err)
+        }
+        actions = append(actions, "priority " + step.Priority.ID + step.Priority.Name)
+    }
+
+    if len(step.Assignee) > 0 {
+        req, err := jiraClient.NewRequest("PUT", "rest/api/2/issue/" + i.IssueKey + "/assignee", step.Assignee)
+        if err != nil {
+            return actions, err
+        }
+        if resp, err := jiraClient.Do(req, nil); err != nil {
+            return actions, jira.NewJiraError(resp, err)
+        }
+        for _, v := range step.Assignee {
+            actions = append(actions, "assignee " + v)
+        }
+    }
+
+    return actions, nil
+}
+
+// escalate applies the escalation steps of the receiver that are due for the issue, every step
+// fires once, steps whose statuses do not match are skipped until a later step fires
+func (api *Api) escalate(cfg *config.Config, i config.Issue) (int, error) {
+    receiver := cfg.GetReceiver(i.Receiver)
+    if receiver == nil || len(receiver.Escalation) == 0 || api.isResolveState(i.StatusId) {
+        return i.Escalation, nil
+    }
+
+    tp := jira.BasicAuthTransport{
+        Username: cfg.Defaults.User,
+        Password: cfg.Defaults.Password,
+    }
+    _, templates := api.state()
+    age := time.Since(time.Unix(i.Updated, 0))
+
+    for n := i.Escalation; n < len(receiver.Escalation); n++ {
+        step := receiver.Escalation[n]
+        if age < step.After {
+            break
+        }
+        if !matchStatus(i, step.Status) {
+            continue
+        }
+
+        data := map[string]interface{}{
+            "issue_key":   i.IssueKey,
+            "status_name": i.StatusName,
+            "receiver":    i.Receiver,
+            "step":        n + 1,
+            "age":         age.Truncate(time.Minute).String(),
+        }
+        comment, err := templates[receiver.Name].Execute(step.Comment, data)
+        if err != nil {
+            return i.Escalation, fmt.Errorf("escalation step %d comment of receiver %s: %v", n + 1, receiver.Name, err)
+        }
+
+        actions, err := applyEscalation(tp, i, step)
+        if err != nil {
+            return i.Escalation, fmt.Errorf("escalate issue %s step %d: %v", i.IssueKey, n + 1, err)
+        }
+
+        // The step is stored before the comment, which would be added twice when the step is retried
+        i.Escalation = n + 1
+        if err := api.Client.UpdateEscalation(i.IssueKey, i.Escalation); err != nil {
+            return n, err
+        }
+
+        if comment != "" {
+            if err := addComment(i.IssueSelf, tp, i.IssueKey, comment); err != nil {
+                log.Printf("[error] escalate issue %s step %d comment: %v", i.IssueKey, n + 1, err)
+            } else {
+                actions = append(actions, "comment")
+            }
+        }
+
+        details := fmt.Sprintf("step %d: %s", n + 1, strings.Join(actions, ", "))
+        log.Printf("[info] issue escalated: %s, %s", i.IssueKey, details)
+        api.saveEvent(config.EventEscalated, i.GroupId, i.IssueKey, details, nil)
+    }
+
+    return i.Escalation, nil
+}
@@ -0,0 +1,116 @@
+package v1
+
+import (
+    "os"
+    "errors"
+    "testing"
+    "io/ioutil"
+    "net/http"
+    "path/filepath"
+    "net/http/httptest"
+    "github.com/andygrunwald/go-jira"
+    "github.com/ltkh/jiramanager/internal/config"
+    "github.com/ltkh/jiramanager/internal/template"
+)
+
+// escalationDb fails UpdateEscalation the given number of times
+type escalationDb struct {
+    fakeDb
+    failures     int
+    step         int
+}
+
+func (f *escalationDb) UpdateEscalation(issue_key string, step int) error {
+    if f.failures > 0 {
+        f.failures--
+        return errors.New("database is locked")
+    }
+    f.step = step
+    return nil
+}
+
+func loadTestTemplate(t *testing.T) *template.Template {
+    dir, err := ioutil.TempDir("", "templates")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { os.RemoveAll(dir) })
+    path := filepath.Join(dir, "test.tmpl")
+    if err := ioutil.WriteFile(path, []byte(`{{ define "test" }}{{ end }}`), 0644); err != nil {
+        t.Fatal(err)
+    }
+    tmpl, err := template.LoadTemplate(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return tmpl
+}
+
+func TestEscalateComments(t *testing.T) {
+    tests := []struct {
+        name         string
+        dbFailures   int
+        commentFails bool
+        comments     int
+        priorities   int
+    }{
+        {"success", 0, false, 1, 1},
+        {"step not stored", 1, false, 1, 2},
+        {"comment fails", 0, true, 1, 1},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            comments, priorities := 0, 0
+            server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                switch {
+                    case r.Method == "POST" && r.URL.Path == "/rest/api/2/issue/TEST-1/comment":
+                        comments++
+                        if tt.commentFails {
+                            w.WriteHeader(500)
+                            return
+                        }
+                        w.WriteHeader(201)
+                        w.Write([]byte(`{"id":"1"}`))
+                    case r.Method == "PUT" && r.URL.Path == "/rest/api/2/issue/TEST-1":
+                        priorities++
+                        w.WriteHeader(204)
+                    default:
+                        t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+                        w.WriteHeader(404)
+                }
+            }))
+            defer server.Close()
+
+            receiver := &config.Receiver{ Name: "jira", Escalation: []*config.Escalation{{
+                After:    1,
+                Priority: jira.Priority{ Name: "High" },
+                Comment:  "{{ .issue_key }} escalated",
+            }}}
+            cfg := &config.Config{ Defaults: &config.Defaults{}, Receivers: []*config.Receiver{receiver} }
+            client := &escalationDb{ failures: tt.dbFailures }
+            api := &Api{ Client: client, Config: cfg, templates: map[string]*template.Template{ "jira": loadTestTemplate(t) } }
+
+            issue := config.Issue{ GroupId: "g1", Receiver: "jira", IssueKey: "TEST-1", IssueSelf: server.URL + "/rest/api/2/issue/1", Updated: 1 }
+
+            // The status updates escalate the issue until the step is stored
+            for pass := 0; pass < 3; pass++ {
+                step, err := api.escalate(cfg, issue)
+                if err == nil && step != 1 {
+                    t.Fatalf("pass %d: escalate() = %d, want step 1", pass, step)
+                }
+                issue.Escalation = step
+            }
+
+            if client.step != 1 {
+                t.Errorf("stored step %d, want 1", client.step)
+            }
+            if comments != tt.comments {
+                t.Errorf("posted %d comments, want %d", comments, tt.comments)
+            }
+            if priorities != tt.priorities {
+                t.Errorf("updated the priority %d times, want %d", priorities, tt.priorities)
+            }
+        })
+    }
+}
@@ -0,0 +1,219 @@
+package v1
+
+import (
+    "fmt"
+    "log"
+    "sync"
+    "time"
+    "net/url"
+    "github.com/andygrunwald/go-jira"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+type flapState struct {
+    receiver     string
+    firing       bool
+    since        time.Time
+    transitions  []time.Time
+    alert        map[string]interface{}
+    reported     bool
+}
+
+// flapDetector keeps the firing and resolved history of every alert group
+type flapDetector struct {
+    sync.Mutex
+    groups       map[string]*flapState
+}
+
+func newFlapDetector() *flapDetector {
+    return &flapDetector{ groups: make(map[string]*flapState) }
+}
+
+func (st *flapState) prune(window time.Duration, now time.Time) {
+    n := 0
+    for _, t := range st.transitions {
+        if now.Sub(t) <= window {
+            st.transitions[n] = t
+            n++
+        }
+    }
+    st.transitions = st.transitions[:n]
+}
+
+// firing records a firing alert of the group and returns whether it is held back until it stays
+// firing for min_firing_duration, flapping alerts are never held back
+func (d *flapDetector) firing(receiver *config.Receiver, group_id string, alert map[string]interface{}, now time.Time) (bool, bool) {
+    fl := receiver.Flapping
+    if fl == nil {
+        return false, false
+    }
+
+    d.Lock()
+    defer d.Unlock()
+
+    st, ok := d.groups[group_id]
+    if !ok {
+        st = &flapState{ receiver: receiver.Name, firing: true, since: now }
+        d.groups[group_id] = st
+    }
+    st.prune(fl.Window, now)
+    if !st.firing {
+        st.firing = true
+        st.since = now
+        st.transitions = append(st.transitions, now)
+    }
+
+    if (fl.Threshold > 0 && len(st.transitions) >= fl.Threshold) || now.Sub(st.since) >= fl.MinFiringDuration {
+        st.alert = nil
+        return false, false
+    }
+
+    added := st.alert == nil
+    st.alert = alert
+    return true, added
+}
+
+// resolved records a resolved alert of the group, a held alert is dropped as it did not stay firing
+func (d *flapDetector) resolved(group_id string, now time.Time) {
+    d.Lock()
+    defer d.Unlock()
+
+    st, ok := d.groups[group_id]
+    if !ok || !st.firing {
+        return
+    }
+    st.firing = false
+    st.transitions = append(st.transitions, now)
+    st.alert = nil
+}
+
+// observe records a firing alert of a group that already has an issue, it returns the transitions
+// within the window and true once when the group starts flapping
+func (d *flapDetector) observe(receiver *config.Receiver, group_id string, now time.Time) (int, bool) {
+    fl := receiver.Flapping
+    if fl == nil || fl.Threshold == 0 {
+        return 0, false
+    }
+
+    d.Lock()
+    defer d.Unlock()
+
+    st, ok := d.groups[group_id]
+    if !ok {
+        st = &flapState{ receiver: receiver.Name, firing: true, since: now }
+        d.groups[group_id] = st
+    }
+    st.prune(fl.Window, now)
+    if !st.firing {
+        st.firing = true
+        st.since = now
+        st.transitions = append(st.transitions, now)
+    }
+
+    if len(st.transitions) < fl.Threshold {
+        st.reported = false
+        return len(st.transitions), false
+    }
+    if st.reported {
+        return len(st.transitions), false
+    }
+    st.reported = true
+    return len(st.transitions), true
+}
+
+// flapping returns the transitions of the group within the window and whether it is flapping
+func (d *flapDetector) flapping(receiver *config.Receiver, group_id string, now time.Time) (int, bool) {
+    fl := receiver.Flapping
+    if fl == nil || fl.Threshold == 0 {
+        return 0, false
+    }
+
+    d.Lock()
+    defer d.Unlock()
+
+    st, ok := d.groups[group_id]
+    if !ok {
+        return 0, false
+    }
+    st.prune(fl.Window, now)
+    st.reported = len(st.transitions) >= fl.Threshold
+    return len(st.transitions), st.reported
+}
+
+// due returns the held alerts of the receiver that stayed firing long enough and forgets the
+// groups without history, all alerts are released when the flapping detection was disabled
+func (d *flapDetector) due(receiver *config.Receiver, now time.Time) []pendingAlert {
+    d.Lock()
+    defer d.Unlock()
+
+    var alerts []pendingAlert
+    fl := receiver.Flapping
+    for group_id, st := range d.groups {
+        if st.receiver != receiver.Name {
+            continue
+        }
+        if fl == nil {
+            if st.alert != nil {
+                alerts = append(alerts, pendingAlert{ groupId: group_id, alert: st.alert })
+            }
+            delete(d.groups, group_id)
+            continue
+        }
+
+        st.prune(fl.Window, now)
+        if st.alert != nil && now.Sub(st.since) >= fl.MinFiringDuration {
+            alerts = append(alerts, pendingAlert{ groupId: group_id, alert: st.alert })
+        }
+        if st.alert == nil && len(st.transitions) == 0 && now.Sub(st.since) > fl.Window {
+            delete(d.groups, group_id)
+        }
+    }
+    return alerts
+}
+
+// held returns the number of alerts of the receiver held back by the flapping detection
+func (d *flapDetector) held(receiver string) int {
+    d.Lock()
+    defer d.Unlock()
+
+    n := 0
+    for _, st := range d.groups {
+        if st.receiver == receiver && st.alert != nil {
+            n++
+        }
+    }
+    return n
+}
+
+// addLabel adds the label to the issue
+func addLabel(self string, tp jira.BasicAuthTransport, key, label string) error {
+    u, err := url.Parse(self)
+    if err != nil {
+        return err
+    }
+
+    jiraClient, err := jira.NewClient(tp.Client(), fmt.Sprintf("%s://%s", u.Scheme, u.Host))
+    if err != nil {
+        return err
+    }
+
+    data := map[string]interface{}{
+        "update": map[string]interface{}{
+            "labels": []map[string]string{ {"add": label} },
+        },
+    }
+    if resp, err := jiraClient.Issue.UpdateIssue(key, data); err != nil {
+        return jira.NewJiraError(resp, err)
+    }
+    return nil
+}
+
+// reportFlapping records the flapping alert of the issue and comments the transitions on it
+func (api *Api) reportFlapping(tp jira.BasicAuthTransport, receiver *config.Receiver, group_id, key, self string, transitions int, alert map[string]interface{}) {
+    details := fmt.Sprintf("%d transitions within %s", transitions, receiver.Flapping.Window)
+    log.Printf("[info] flapping alert: %s, %s", key, details)
+    api.saveEvent(config.EventFlapping, group_id, key, details, alert)
+    if err := addComment(self, tp, key, "The alert is flapping: " + details + " between firing and resolved."); err != nil {
+        log.Printf("[error] comment issue %s: %v", key, err)
+    }
+}
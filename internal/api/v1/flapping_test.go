@@ -0,0 +1,162 @@
+package v1
+
+import (
+    "time"
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "github.com/ltkh/jiramanager/internal/config"
+    "github.com/ltkh/jiramanager/internal/template"
+)
+
+func TestFlapDetectorHold(t *testing.T) {
+    receiver := &config.Receiver{ Name: "jira", Flapping: &config.Flapping{ MinFiringDuration: time.Minute, Threshold: 3, Window: time.Hour } }
+    d := newFlapDetector()
+    now := time.Now()
+    alert := map[string]interface{}{ "status": "firing" }
+
+    if hold, added := d.firing(receiver, "g1", alert, now); !hold || !added {
+        t.Errorf("firing() = %v, %v, want the new alert held", hold, added)
+    }
+    if hold, added := d.firing(receiver, "g1", alert, now.Add(time.Second)); !hold || added {
+        t.Errorf("firing() again = %v, %v, want the alert held once", hold, added)
+    }
+    if n := d.held("jira"); n != 1 {
+        t.Errorf("held() = %d, want 1", n)
+    }
+    if due := d.due(receiver, now.Add(30 * time.Second)); len(due) != 0 {
+        t.Errorf("due() before min_firing_duration = %v", due)
+    }
+    if due := d.due(receiver, now.Add(time.Minute)); len(due) != 1 || due[0].groupId != "g1" {
+        t.Errorf("due() after min_firing_duration = %v, want g1", due)
+    }
+
+    // A resolved alert is dropped
+    d.firing(receiver, "g2", alert, now)
+    d.resolved("g2", now.Add(time.Second))
+    if due := d.due(receiver, now.Add(time.Hour)); len(due) != 1 || due[0].groupId != "g1" {
+        t.Errorf("due() = %v, want only g1", due)
+    }
+
+    // Reaching the threshold releases the alert as flapping
+    now = now.Add(time.Second)
+    if hold, _ := d.firing(receiver, "g2", alert, now); !hold {
+        t.Fatal("firing() below the threshold did not hold the alert")
+    }
+    d.resolved("g2", now.Add(time.Second))
+    if hold, _ := d.firing(receiver, "g2", alert, now.Add(2 * time.Second)); hold {
+        t.Error("firing() of a flapping alert held it")
+    }
+    if n, flapping := d.flapping(receiver, "g2", now.Add(2 * time.Second)); !flapping || n != 4 {
+        t.Errorf("flapping() = %d, %v, want 4 transitions", n, flapping)
+    }
+
+    // Transitions outside the window are forgotten
+    if n, flapping := d.flapping(receiver, "g2", now.Add(2 * time.Hour)); flapping || n != 0 {
+        t.Errorf("flapping() after the window = %d, %v", n, flapping)
+    }
+
+    // Held alerts are released when the detection is disabled
+    d.firing(receiver, "g3", alert, now)
+    if due := d.due(&config.Receiver{ Name: "jira" }, now); len(due) != 2 {
+        t.Errorf("due() without flapping = %v, want g1 and g3", due)
+    }
+    if n := d.held("jira"); n != 0 {
+        t.Errorf("held() without flapping = %d", n)
+    }
+}
+
+func TestFlapDetectorObserve(t *testing.T) {
+    receiver := &config.Receiver{ Name: "jira", Flapping: &config.Flapping{ Threshold: 3, Window: time.Hour } }
+    d := newFlapDetector()
+    now := time.Now()
+    alert := map[string]interface{}{ "status": "firing" }
+
+    // Without min_firing_duration the issue is created right away
+    if hold, _ := d.firing(receiver, "g1", alert, now); hold {
+        t.Fatal("firing() held the alert without min_firing_duration")
+    }
+    if _, flapping := d.flapping(receiver, "g1", now); flapping {
+        t.Fatal("flapping() of a new alert")
+    }
+
+    // Later transitions are recorded for the existing issue, which is reported once
+    var reported []int
+    for i := 1; i <= 6; i++ {
+        d.resolved("g1", now.Add(time.Duration(i) * time.Second))
+        if n, flapping := d.observe(receiver, "g1", now.Add(time.Duration(i) * time.Second)); flapping {
+            reported = append(reported, n)
+        }
+    }
+    if len(reported) != 1 || reported[0] != 4 {
+        t.Errorf("observe() reported %v, want once at 4 transitions", reported)
+    }
+
+    // The group is reported again after it calmed down
+    later := now.Add(2 * time.Hour)
+    if _, flapping := d.observe(receiver, "g1", later); flapping {
+        t.Error("observe() after the window reported the group")
+    }
+    for i := 1; i <= 2; i++ {
+        d.resolved("g1", later.Add(time.Duration(i) * time.Second))
+        if n, flapping := d.observe(receiver, "g1", later.Add(time.Duration(i) * time.Second)); flapping != (i == 2) {
+            t.Errorf("observe() after %d more transitions = %d, %v", i, n, flapping)
+        }
+    }
+
+    // Groups without threshold are not observed
+    if _, flapping := d.observe(&config.Receiver{ Name: "jira", Flapping: &config.Flapping{ MinFiringDuration: time.Minute } }, "g2", now); flapping {
+        t.Error("observe() without threshold reported the group")
+    }
+}
+
+// flapDb returns the stored issue of every alert
+type flapDb struct {
+    fakeDb
+    issue        config.Issue
+}
+
+func (f *flapDb) LoadIssue(group_id string) (config.Issue, bool, error) {
+    return f.issue, true, nil
+}
+
+func (f *flapDb) LoadSilences(filter config.SilenceFilter) ([]config.Silence, error) {
+    return nil, nil
+}
+
+func TestProcessAlertFlappingIssue(t *testing.T) {
+    var requests []string
+    jira := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests = append(requests, r.Method + " " + r.URL.Path)
+        w.Write([]byte(`{}`))
+    }))
+    defer jira.Close()
+
+    receiver := &config.Receiver{ Name: "jira", Flapping: &config.Flapping{ Threshold: 2, Window: time.Hour, Label: "flapping" } }
+    cfg := &config.Config{
+        Defaults:   &config.Defaults{},
+        Receivers:  []*config.Receiver{receiver},
+    }
+    client := &flapDb{ issue: config.Issue{ IssueKey: "TEST-1", IssueSelf: jira.URL + "/rest/api/2/issue/1", Receiver: "jira" } }
+    api := &Api{ Client: client, Config: cfg, limits: newLimiter(), silences: newSilenceCache(), flaps: newFlapDetector(), recorded: newEventRecorder() }
+    templates := map[string]*template.Template{ "jira": &template.Template{} }
+
+    labels := map[string]interface{}{ "alertname": "up" }
+    for _, status := range []string{"firing", "resolved", "firing", "resolved", "firing"} {
+        api.processAlert(cfg, templates, receiver, map[string]interface{}{ "status": status, "labels": labels })
+    }
+
+    want := []string{"PUT /rest/api/2/issue/TEST-1", "POST /rest/api/2/issue/TEST-1/comment"}
+    if len(requests) != len(want) || requests[0] != want[0] || requests[1] != want[1] {
+        t.Errorf("Jira requests %v, want %v", requests, want)
+    }
+    flapping := 0
+    for _, e := range client.events {
+        if e.Event == config.EventFlapping {
+            flapping++
+        }
+    }
+    if flapping != 1 {
+        t.Errorf("%d flapping events, want 1", flapping)
+    }
+}
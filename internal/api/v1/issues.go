@@ -0,0 +1,185 @@
+package v1
+
+import (
+    "fmt"
+    "log"
+    "sort"
+    "time"
+    "strings"
+    "strconv"
+    "net/http"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+type IssueList struct {
+    Total        int                          `json:"total"`
+    Offset       int                          `json:"offset"`
+    Limit        int                          `json:"limit"`
+    Issues       []config.Issue               `json:"issues"`
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+    w.WriteHeader(code)
+    w.Write(encodeResp(&Resp{Status:"error", Error:err.Error()}))
+}
+
+func parseInt(value string, def int) (int, error) {
+    if value == "" {
+        return def, nil
+    }
+    i, err := strconv.Atoi(value)
+    if err != nil || i < 0 {
+        return 0, fmt.Errorf("invalid number %q", value)
+    }
+    return i, nil
+}
+
+func parseDuration(value string) (time.Duration, error) {
+    if value == "" {
+        return 0, nil
+    }
+    d, err := time.ParseDuration(value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid duration %q", value)
+    }
+    return d, nil
+}
+
+// ApiIssues lists tracked issues, filtered by status, receiver and age
+func (api *Api) ApiIssues(w http.ResponseWriter, r *http.Request) {
+    if r.Method != "GET" {
+        writeError(w, 405, fmt.Errorf("method %s not allowed", r.Method))
+        return
+    }
+
+    query := r.URL.Query()
+
+    offset, err := parseInt(query.Get("offset"), 0)
+    if err != nil {
+        writeError(w, 400, err)
+        return
+    }
+    limit, err := parseInt(query.Get("limit"), 100)
+    if err != nil {
+        writeError(w, 400, err)
+        return
+    }
+    minAge, err := parseDuration(query.Get("min_age"))
+    if err != nil {
+        writeError(w, 400, err)
+        return
+    }
+    maxAge, err := parseDuration(query.Get("max_age"))
+    if err != nil {
+        writeError(w, 400, err)
+        return
+    }
+
+    issues, err := api.Client.LoadIssues()
+    if err != nil {
+        log.Printf("[error] %v - %s", err, r.URL.Path)
+        writeError(w, 500, err)
+        return
+    }
+
+    status := query.Get("status")
+    receiver := query.Get("receiver")
+    now := time.Now().UTC().Unix()
+
+    result := []config.Issue{}
+    for _, i := range issues {
+        if status != "" && status != i.StatusId && !strings.EqualFold(status, i.StatusName) {
+            continue
+        }
+        if receiver != "" && receiver != i.Receiver {
+            continue
+        }
+        age := time.Duration(now - i.Created) * time.Second
+        if minAge > 0 && age < minAge {
+            continue
+        }
+        if maxAge > 0 && age > maxAge {
+            continue
+        }
+        result = append(result, i)
+    }
+
+    sort.Slice(result, func(a, b int) bool {
+        if result[a].Created == result[b].Created {
+            return result[a].GroupId < result[b].GroupId
+        }
+        return result[a].Created > result[b].Created
+    })
+
+    list := IssueList{ Total: len(result), Offset: offset, Limit: limit }
+    if offset < len(result) {
+        end := len(result)
+        if limit > 0 && offset + limit < end {
+            end = offset + limit
+        }
+        list.Issues = result[offset:end]
+    } else {
+        list.Issues = []config.Issue{}
+    }
+
+    w.Write(encodeResp(&Resp{Status:"success", Data:list}))
+}
+
+// ApiIssue serves /api/v1/issues/{group_id} and /api/v1/issues/{group_id}/resync
+func (api *Api) ApiIssue(w http.ResponseWriter, r *http.Request) {
+    path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/issues"), "/")
+    if path == "" {
+        api.ApiIssues(w, r)
+        return
+    }
+
+    parts := strings.Split(path, "/")
+    if len(parts) > 2 || (len(parts) == 2 && parts[1] != "resync") {
+        writeError(w, 404, fmt.Errorf("page not found"))
+        return
+    }
+    group_id := parts[0]
+
+    issue, found, err := api.Client.LoadIssue(group_id)
+    if err != nil {
+        log.Printf("[error] %v - %s", err, r.URL.Path)
+        writeError(w, 500, err)
+        return
+    }
+    if !found {
+        writeError(w, 404, fmt.Errorf("issue %q not found", group_id))
+        return
+    }
+
+    if len(parts) == 2 {
+        if r.Method != "POST" {
+            writeError(w, 405, fmt.Errorf("method %s not allowed", r.Method))
+            return
+        }
+        issue, err = api.syncIssue(issue)
+        if err != nil {
+            log.Printf("[error] %v - %s", err, r.URL.Path)
+            writeError(w, 502, err)
+            return
+        }
+        w.Write(encodeResp(&Resp{Status:"success", Data:issue}))
+        return
+    }
+
+    switch r.Method {
+        case "GET":
+            w.Write(encodeResp(&Resp{Status:"success", Data:issue}))
+        case "DELETE":
+            if err := api.Client.DeleteIssue(group_id); err != nil {
+                log.Printf("[error] %v - %s", err, r.URL.Path)
+                writeError(w, 500, err)
+                return
+            }
+            api.recorded.forget(group_id)
+            log.Printf("[info] issue removed from database: %s", issue.IssueKey)
+            api.saveEvent(config.EventPurged, group_id, issue.IssueKey, "api", nil)
+            w.Write(encodeResp(&Resp{Status:"success"}))
+        default:
+            writeError(w, 405, fmt.Errorf("method %s not allowed", r.Method))
+    }
+}
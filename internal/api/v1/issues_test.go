@@ -0,0 +1,70 @@
+package v1
+
+import (
+    "testing"
+    "encoding/json"
+    "net/http/httptest"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+// issuesDb keeps the stored issues by group id
+type issuesDb struct {
+    fakeDb
+    issues       map[string]config.Issue
+}
+
+func (f *issuesDb) LoadIssue(group_id string) (config.Issue, bool, error) {
+    i, ok := f.issues[group_id]
+    return i, ok, nil
+}
+
+func (f *issuesDb) DeleteIssue(group_id string) error {
+    delete(f.issues, group_id)
+    return nil
+}
+
+func TestApiIssue(t *testing.T) {
+    client := &issuesDb{ issues: map[string]config.Issue{
+        "g1": { GroupId: "g1", IssueKey: "TEST-1", Receiver: "jira" },
+    }}
+    api := &Api{ Client: client, recorded: newEventRecorder() }
+    api.recorded.first("g1", config.EventDeduplicated + "TEST-1")
+
+    tests := []struct {
+        name     string
+        method   string
+        path     string
+        code     int
+    }{
+        {"get", "GET", "/api/v1/issues/g1", 200},
+        {"unknown issue", "GET", "/api/v1/issues/missing", 404},
+        {"unknown path", "GET", "/api/v1/issues/g1/comments", 404},
+        {"method not allowed", "PUT", "/api/v1/issues/g1", 405},
+        {"delete unknown issue", "DELETE", "/api/v1/issues/missing", 404},
+        {"delete", "DELETE", "/api/v1/issues/g1", 200},
+        {"get deleted issue", "GET", "/api/v1/issues/g1", 404},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        api.ApiIssue(w, httptest.NewRequest(tt.method, tt.path, nil))
+        if w.Code != tt.code {
+            t.Errorf("%s: %s %s = %d, want %d: %s", tt.name, tt.method, tt.path, w.Code, tt.code, w.Body.String())
+        }
+        if tt.name == "get" {
+            var resp struct {
+                Data     config.Issue
+            }
+            if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data.IssueKey != "TEST-1" {
+                t.Errorf("get: response %s", w.Body.String())
+            }
+        }
+    }
+
+    if len(client.events) != 1 || client.events[0].Event != config.EventPurged || client.events[0].IssueKey != "TEST-1" {
+        t.Errorf("events %+v, want one purged event of TEST-1", client.events)
+    }
+    // The deduplication of a new issue of the group is recorded again
+    if !api.recorded.first("g1", config.EventDeduplicated + "TEST-1") {
+        t.Error("the recorded events of the deleted issue were kept")
+    }
+}
@@ -0,0 +1,419 @@
+package v1
+
+import (
+    "fmt"
+    "log"
+    "sort"
+    "sync"
+    "time"
+    "strings"
+    "net/http"
+    "github.com/andygrunwald/go-jira"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+// Maximum number of alerts held back per receiver by the queue and aggregate overflow
+const maxPending = 1000
+
+// bucket is a token bucket refilled continuously up to its size
+type bucket struct {
+    tokens       float64
+    size         float64
+    rate         float64
+    last         time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+    b.tokens += now.Sub(b.last).Seconds() * b.rate
+    if b.tokens > b.size {
+        b.tokens = b.size
+    }
+    b.last = now
+}
+
+type pendingAlert struct {
+    groupId      string
+    alert        map[string]interface{}
+}
+
+type LimitStatus struct {
+    Scope        string                       `json:"scope"`
+    PerMinute    int                          `json:"per_minute,omitempty"`
+    PerHour      int                          `json:"per_hour,omitempty"`
+    MaxOpen      int                          `json:"max_open,omitempty"`
+    Open         int                          `json:"open"`
+    Throttled    int64                        `json:"throttled"`
+}
+
+type OverflowStatus struct {
+    Receiver     string                       `json:"receiver"`
+    Overflow     string                       `json:"overflow"`
+    Dropped      int64                        `json:"dropped"`
+    Queued       int64                        `json:"queued"`
+    Aggregated   int64                        `json:"aggregated"`
+    Pending      int                          `json:"pending"`
+    Storm        bool                         `json:"storm"`
+    Suppressed   int64                        `json:"suppressed"`
+}
+
+type Limits struct {
+    Limits       []LimitStatus                `json:"limits"`
+    Receivers    []OverflowStatus             `json:"receivers"`
+}
+
+// limiter keeps the token buckets, the alerts held back and the throttling counters
+type limiter struct {
+    sync.Mutex
+    buckets      map[string]*bucket
+    throttled    map[string]int64
+    overflows    map[string]map[string]int64
+    queued       map[string][]pendingAlert
+    aggregated   map[string][]pendingAlert
+    deferred     map[string][]pendingAlert
+}
+
+func newLimiter() *limiter {
+    return &limiter{
+        buckets:    make(map[string]*bucket),
+        throttled:  make(map[string]int64),
+        overflows:  make(map[string]map[string]int64),
+        queued:     make(map[string][]pendingAlert),
+        aggregated: make(map[string][]pendingAlert),
+        deferred:   make(map[string][]pendingAlert),
+    }
+}
+
+// getBucket returns the bucket of a limit, resized when the limit has been reloaded
+func (l *limiter) getBucket(key string, size int, period time.Duration, now time.Time) *bucket {
+    b, ok := l.buckets[key]
+    if !ok {
+        b = &bucket{ tokens: float64(size), last: now }
+        l.buckets[key] = b
+    }
+    b.size = float64(size)
+    b.rate = float64(size) / period.Seconds()
+    b.refill(now)
+    return b
+}
+
+// projectName returns the name the project is configured with in project_limits
+func projectName(project jira.Project) string {
+    switch {
+        case project.Key != "":
+            return project.Key
+        case project.ID != "":
+            return project.ID
+    }
+    return project.Name
+}
+
+type scopedLimit struct {
+    scope        string
+    limit        *config.RateLimit
+    match        func(config.Issue) bool
+}
+
+// receiverLimits returns the limits that apply to the issues created by the receiver
+func receiverLimits(cfg *config.Config, receiver *config.Receiver) []scopedLimit {
+    var limits []scopedLimit
+
+    if receiver.RateLimit != nil {
+        limits = append(limits, scopedLimit{
+            scope: "receiver " + receiver.Name,
+            limit: receiver.RateLimit,
+            match: func(i config.Issue) bool { return i.Receiver == receiver.Name && i.GroupId != aggregateGroup(receiver.Name) },
+        })
+    }
+
+    project := projectName(receiver.Project)
+    if rl, ok := cfg.ProjectLimits[project]; ok && rl != nil {
+        limits = append(limits, scopedLimit{
+            scope: "project " + project,
+            limit: rl,
+            match: func(i config.Issue) bool {
+                if rc := cfg.GetReceiver(i.Receiver); rc != nil {
+                    return projectName(rc.Project) == project
+                }
+                return strings.SplitN(i.IssueKey, "-", 2)[0] == project
+            },
+        })
+    }
+
+    return limits
+}
+
+// countOpen returns the number of issues matching the limit that are not in a resolve state,
+// alert groups stored with an umbrella issue count once
+func (api *Api) countOpen(issues []config.Issue, match func(config.Issue) bool) int {
+    keys := make(map[string]bool)
+    for _, i := range issues {
+        if match(i) && !api.isResolveState(i.StatusId) {
+            keys[i.IssueKey] = true
+        }
+    }
+    return len(keys)
+}
+
+// allow takes a token from every limit of the receiver, or none when one of them is exhausted,
+// and returns the scope and the description of the exhausted limit
+func (api *Api) allow(cfg *config.Config, receiver *config.Receiver, issues []config.Issue) (string, string, bool) {
+    limits := receiverLimits(cfg, receiver)
+
+    for _, sl := range limits {
+        if sl.limit.MaxOpen > 0 && api.countOpen(issues, sl.match) >= sl.limit.MaxOpen {
+            return sl.scope, fmt.Sprintf("%s max_open %d", sl.scope, sl.limit.MaxOpen), false
+        }
+    }
+
+    l := api.limits
+    l.Lock()
+    defer l.Unlock()
+
+    now := time.Now()
+    var buckets []*bucket
+    for _, sl := range limits {
+        periods := []struct{ name string; size int; period time.Duration }{
+            {"per_minute", sl.limit.PerMinute, time.Minute},
+            {"per_hour", sl.limit.PerHour, time.Hour},
+        }
+        for _, p := range periods {
+            if p.size <= 0 {
+                continue
+            }
+            b := l.getBucket(sl.scope + " " + p.name, p.size, p.period, now)
+            if b.tokens < 1 {
+                return sl.scope, fmt.Sprintf("%s %s %d", sl.scope, p.name, p.size), false
+            }
+            buckets = append(buckets, b)
+        }
+    }
+
+    for _, b := range buckets {
+        b.tokens--
+    }
+    return "", "", true
+}
+
+func (l *limiter) countOverflow(receiver, overflow string) {
+    if l.overflows[receiver] == nil {
+        l.overflows[receiver] = make(map[string]int64)
+    }
+    l.overflows[receiver][overflow]++
+}
+
+// countDropped counts an alert dropped because the alerts held back for the receiver reached maxPending
+func (l *limiter) countDropped(receiver, group_id, held string) {
+    log.Printf("[warn] %d alerts of receiver %s are %s already, alert %s dropped", maxPending, receiver, held, group_id)
+    l.countOverflow(receiver, config.OverflowDrop)
+}
+
+// hold keeps an alert for later, an alert of the same group replaces the one held before.
+// It returns whether the alert was held for the first time and whether it was dropped
+// because the buffer is full
+func hold(alerts []pendingAlert, pa pendingAlert) ([]pendingAlert, bool, bool) {
+    for i := range alerts {
+        if alerts[i].groupId == pa.groupId {
+            alerts[i] = pa
+            return alerts, false, false
+        }
+    }
+    if len(alerts) >= maxPending {
+        return alerts, false, true
+    }
+    return append(alerts, pa), true, false
+}
+
+// restore puts the alerts of a report that failed back in front of the alerts held meanwhile,
+// a newer alert of the same group is kept, and returns the number of alerts dropped at maxPending
+func restore(held, alerts []pendingAlert) ([]pendingAlert, int) {
+    newer := make(map[string]bool, len(held))
+    for _, pa := range held {
+        newer[pa.groupId] = true
+    }
+    result := make([]pendingAlert, 0, len(alerts) + len(held))
+    for _, pa := range alerts {
+        if !newer[pa.groupId] {
+            result = append(result, pa)
+        }
+    }
+    result = append(result, held...)
+    if len(result) > maxPending {
+        return result[:maxPending], len(result) - maxPending
+    }
+    return result, 0
+}
+
+// overflow applies the overflow behaviour of the receiver to an alert throttled by the limit
+// of the scope and returns whether the alert was held back for the first time, the throttled
+// counter of the scope is not increased again when a held alert is retried
+func (l *limiter) overflow(receiver *config.Receiver, scope, group_id string, alert map[string]interface{}) (string, bool) {
+    l.Lock()
+    defer l.Unlock()
+
+    mode := config.OverflowDrop
+    if receiver.RateLimit != nil && receiver.RateLimit.Overflow != "" {
+        mode = receiver.RateLimit.Overflow
+    }
+
+    added, dropped := true, false
+    pa := pendingAlert{ groupId: group_id, alert: alert }
+    switch mode {
+        case config.OverflowQueue:
+            l.queued[receiver.Name], added, dropped = hold(l.queued[receiver.Name], pa)
+            if dropped {
+                l.countDropped(receiver.Name, group_id, "queued")
+            }
+        case config.OverflowAggregate:
+            l.aggregated[receiver.Name], added, dropped = hold(l.aggregated[receiver.Name], pa)
+            if dropped {
+                l.countDropped(receiver.Name, group_id, "waiting for the summary issue")
+            }
+    }
+    if added {
+        l.throttled[scope]++
+        l.countOverflow(receiver.Name, mode)
+    }
+
+    return mode, added
+}
+
+// forget removes the alerts of a group held back for the receiver
+func (l *limiter) forget(receiver, group_id string) {
+    l.Lock()
+    defer l.Unlock()
+
+    remove := func(alerts []pendingAlert) []pendingAlert {
+        result := alerts[:0]
+        for _, pa := range alerts {
+            if pa.groupId != group_id {
+                result = append(result, pa)
+            }
+        }
+        return result
+    }
+    l.queued[receiver] = remove(l.queued[receiver])
+    l.aggregated[receiver] = remove(l.aggregated[receiver])
+    l.deferred[receiver] = remove(l.deferred[receiver])
+}
+
+// deferAlert keeps an alert arriving within a mute time interval until the interval ends
+// and returns false when the alert was already deferred
+func (l *limiter) deferAlert(receiver, group_id string, alert map[string]interface{}) bool {
+    l.Lock()
+    defer l.Unlock()
+    added, dropped := false, false
+    l.deferred[receiver], added, dropped = hold(l.deferred[receiver], pendingAlert{ groupId: group_id, alert: alert })
+    if dropped {
+        l.countDropped(receiver, group_id, "deferred")
+    }
+    return added
+}
+
+// takeDeferred returns and removes the deferred alerts of the receiver
+func (l *limiter) takeDeferred(receiver string) []pendingAlert {
+    l.Lock()
+    defer l.Unlock()
+    alerts := l.deferred[receiver]
+    delete(l.deferred, receiver)
+    return alerts
+}
+
+// getQueued returns the queued alerts of the receiver, they are removed once allowed or resolved
+func (l *limiter) getQueued(receiver string) []pendingAlert {
+    l.Lock()
+    defer l.Unlock()
+    return append([]pendingAlert{}, l.queued[receiver]...)
+}
+
+// takeAggregated returns and removes the alerts of the receiver waiting for the summary issue
+func (l *limiter) takeAggregated(receiver string) []pendingAlert {
+    l.Lock()
+    defer l.Unlock()
+    alerts := l.aggregated[receiver]
+    delete(l.aggregated, receiver)
+    return alerts
+}
+
+// restoreAggregated puts the alerts back when the summary issue could not be created or commented
+// and returns the number of alerts dropped at maxPending
+func (l *limiter) restoreAggregated(receiver string, alerts []pendingAlert) int {
+    l.Lock()
+    defer l.Unlock()
+    var dropped int
+    l.aggregated[receiver], dropped = restore(l.aggregated[receiver], alerts)
+    return dropped
+}
+
+// countRestoreDropped counts the alerts of a failed report that did not fit back into the buffer
+func (l *limiter) countRestoreDropped(receiver, held string, dropped int) {
+    if dropped == 0 {
+        return
+    }
+    l.Lock()
+    defer l.Unlock()
+    log.Printf("[warn] %d alerts of receiver %s are %s already, %d alerts dropped", maxPending, receiver, held, dropped)
+    for i := 0; i < dropped; i++ {
+        l.countOverflow(receiver, config.OverflowDrop)
+    }
+}
+
+// ApiLimits returns the configured rate limits with the throttling counters
+func (api *Api) ApiLimits(w http.ResponseWriter, r *http.Request) {
+    if r.Method != "GET" {
+        writeError(w, 405, fmt.Errorf("method %s not allowed", r.Method))
+        return
+    }
+
+    cfg, _ := api.state()
+    issues, err := api.Client.LoadIssues()
+    if err != nil {
+        writeError(w, 500, err)
+        return
+    }
+
+    result := Limits{ Limits: []LimitStatus{}, Receivers: []OverflowStatus{} }
+    seen := make(map[string]bool)
+
+    for _, receiver := range cfg.Receivers {
+        for _, sl := range receiverLimits(cfg, receiver) {
+            if seen[sl.scope] {
+                continue
+            }
+            seen[sl.scope] = true
+            result.Limits = append(result.Limits, LimitStatus{
+                Scope:      sl.scope,
+                PerMinute:  sl.limit.PerMinute,
+                PerHour:    sl.limit.PerHour,
+                MaxOpen:    sl.limit.MaxOpen,
+                Open:       api.countOpen(issues, sl.match),
+            })
+        }
+    }
+    sort.Slice(result.Limits, func(i, j int) bool { return result.Limits[i].Scope < result.Limits[j].Scope })
+
+    api.limits.Lock()
+    defer api.limits.Unlock()
+
+    for i := range result.Limits {
+        result.Limits[i].Throttled = api.limits.throttled[result.Limits[i].Scope]
+    }
+
+    for _, receiver := range cfg.Receivers {
+        status := OverflowStatus{
+            Receiver:   receiver.Name,
+            Overflow:   config.OverflowDrop,
+            Dropped:    api.limits.overflows[receiver.Name][config.OverflowDrop],
+            Queued:     api.limits.overflows[receiver.Name][config.OverflowQueue],
+            Aggregated: api.limits.overflows[receiver.Name][config.OverflowAggregate],
+            Pending:    len(api.limits.queued[receiver.Name]) + len(api.limits.aggregated[receiver.Name]) + len(api.limits.deferred[receiver.Name]) + api.flaps.held(receiver.Name),
+            Storm:      api.storms.isActive(receiver.Name),
+            Suppressed: api.silenced.receiver(receiver.Name),
+        }
+        if receiver.RateLimit != nil && receiver.RateLimit.Overflow != "" {
+            status.Overflow = receiver.RateLimit.Overflow
+        }
+        result.Receivers = append(result.Receivers, status)
+    }
+
+    w.Write(encodeResp(&Resp{Status:"success", Data:result}))
+}
@@ -0,0 +1,68 @@
+package v1
+
+import (
+    "fmt"
+    "testing"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+func TestOverflowCounters(t *testing.T) {
+    receiver := &config.Receiver{ Name: "jira", RateLimit: &config.RateLimit{ PerMinute: 1, Overflow: config.OverflowQueue } }
+    scope := "receiver jira"
+    l := newLimiter()
+    alert := map[string]interface{}{ "status": "firing" }
+
+    // A queued alert retried while the limit is exhausted is throttled once
+    for i := 0; i < 3; i++ {
+        if _, added := l.overflow(receiver, scope, "g1", alert); added != (i == 0) {
+            t.Errorf("retry %d: added = %v", i, added)
+        }
+    }
+    if l.throttled[scope] != 1 || l.overflows["jira"][config.OverflowQueue] != 1 {
+        t.Errorf("throttled = %d, queued = %d, want 1 and 1", l.throttled[scope], l.overflows["jira"][config.OverflowQueue])
+    }
+
+    // Alerts above maxPending are dropped and counted
+    for i := 1; i < maxPending; i++ {
+        l.overflow(receiver, scope, fmt.Sprintf("g%d", i + 1), alert)
+    }
+    if len(l.queued["jira"]) != maxPending {
+        t.Fatalf("queued %d alerts, want %d", len(l.queued["jira"]), maxPending)
+    }
+    if _, added := l.overflow(receiver, scope, "full", alert); added {
+        t.Error("alert above maxPending was queued")
+    }
+    if len(l.queued["jira"]) != maxPending || l.overflows["jira"][config.OverflowDrop] != 1 {
+        t.Errorf("queued = %d, dropped = %d, want %d and 1", len(l.queued["jira"]), l.overflows["jira"][config.OverflowDrop], maxPending)
+    }
+    if l.throttled[scope] != int64(maxPending) {
+        t.Errorf("throttled = %d, want %d", l.throttled[scope], maxPending)
+    }
+}
+
+func TestRestore(t *testing.T) {
+    alert := func(group string, n int) pendingAlert {
+        return pendingAlert{ groupId: group, alert: map[string]interface{}{ "n": n } }
+    }
+
+    // Alerts of a failed report go back in front, a newer alert of the same group is kept
+    held := []pendingAlert{ alert("b", 2), alert("c", 2) }
+    result, dropped := restore(held, []pendingAlert{ alert("a", 1), alert("b", 1) })
+    if dropped != 0 || len(result) != 3 {
+        t.Fatalf("restore() = %v, %d", result, dropped)
+    }
+    for i, want := range []pendingAlert{ alert("a", 1), alert("b", 2), alert("c", 2) } {
+        if result[i].groupId != want.groupId || result[i].alert["n"] != want.alert["n"] {
+            t.Errorf("restore()[%d] = %v, want %v", i, result[i], want)
+        }
+    }
+
+    var full []pendingAlert
+    for i := 0; i < maxPending; i++ {
+        full = append(full, alert(fmt.Sprintf("g%d", i), 1))
+    }
+    result, dropped = restore(full, []pendingAlert{ alert("a", 1), alert("b", 1) })
+    if dropped != 2 || len(result) != maxPending || result[0].groupId != "a" {
+        t.Errorf("restore() into a full buffer kept %d alerts starting with %s, dropped %d", len(result), result[0].groupId, dropped)
+    }
+}
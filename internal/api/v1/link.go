@@ -0,0 +1,146 @@
+package v1
+
+import (
+    "fmt"
+    "log"
+    "net/url"
+    "strings"
+    "github.com/andygrunwald/go-jira"
+    "github.com/ltkh/jiramanager/internal/config"
+    "github.com/ltkh/jiramanager/internal/template"
+)
+
+// linkKey returns the values of the link_by labels of the alert, or an empty key when one is missing
+func linkKey(receiver *config.Receiver, alert map[string]interface{}) string {
+    if len(receiver.LinkBy) == 0 {
+        return ""
+    }
+
+    labels, _ := alert["labels"].(map[string]interface{})
+
+    var pairs []string
+    for _, name := range receiver.LinkBy {
+        value, ok := labels[name].(string)
+        if !ok || value == "" {
+            return ""
+        }
+        pairs = append(pairs, name + "=" + value)
+    }
+    return strings.Join(pairs, ",")
+}
+
+// parentGroup returns the group id of the parent issue of the link key
+func parentGroup(receiver, link_key string) string {
+    return getHash("parent:" + receiver + ":" + link_key)
+}
+
+// latestOpen returns the most recently created open issue with the link key
+func (api *Api) latestOpen(issues []config.Issue, link_key string) (config.Issue, bool) {
+    var latest config.Issue
+    found := false
+    for _, i := range issues {
+        if i.LinkKey != link_key || api.isResolveState(i.StatusId) {
+            continue
+        }
+        if !found || i.Created > latest.Created {
+            latest = i
+            found = true
+        }
+    }
+    return latest, found
+}
+
+// addLink links the new issue to an existing one, the new issue is the inward issue of the link
+func addLink(self string, tp jira.BasicAuthTransport, linkType, key, existing string) error {
+    u, err := url.Parse(self)
+    if err != nil {
+        return err
+    }
+
+    jiraClient, err := jira.NewClient(tp.Client(), fmt.Sprintf("%s://%s", u.Scheme, u.Host))
+    if err != nil {
+        return err
+    }
+
+    // Issue.AddLink also sends the empty inward and outward descriptions of the link type
+    payload := map[string]interface{}{
+        "type":         map[string]string{"name": linkType},
+        "inwardIssue":  map[string]string{"key": key},
+        "outwardIssue": map[string]string{"key": existing},
+    }
+    req, err := jiraClient.NewRequest("POST", "rest/api/2/issueLink", payload)
+    if err != nil {
+        return err
+    }
+
+    resp, err := jiraClient.Do(req, nil)
+    if err != nil {
+        return jira.NewJiraError(resp, err)
+    }
+    return nil
+}
+
+// linkIssue links a new issue to the most recent open issue with the same link key
+func (api *Api) linkIssue(tp jira.BasicAuthTransport, receiver *config.Receiver, issues []config.Issue, tk config.Issue) {
+    prev, ok := api.latestOpen(issues, tk.LinkKey)
+    if !ok {
+        return
+    }
+
+    if err := addLink(tk.IssueSelf, tp, receiver.LinkType, tk.IssueKey, prev.IssueKey); err != nil {
+        log.Printf("[error] link issue %s to %s: %v", tk.IssueKey, prev.IssueKey, err)
+        return
+    }
+
+    log.Printf("[info] link issue: %s %s %s", tk.IssueKey, receiver.LinkType, prev.IssueKey)
+    api.saveEvent(config.EventLinked, tk.GroupId, tk.IssueKey, receiver.LinkType + " " + prev.IssueKey, nil)
+}
+
+// parentIssue returns the key of the open parent issue of the link key, the parent is created
+// from the parent_mode templates with the first alert when there is none
+func (api *Api) parentIssue(tp jira.BasicAuthTransport, receiver *config.Receiver, tmpl *template.Template, link_key string, alert map[string]interface{}, dryRun bool) (string, error) {
+    group_id := parentGroup(receiver.Name, link_key)
+
+    task, found, err := api.Client.LoadIssue(group_id)
+    if err != nil {
+        return "", err
+    }
+    if found && !api.isResolveState(task.StatusId) {
+        return task.IssueKey, nil
+    }
+
+    is, issueADF, err := RenderParent(receiver, tmpl, alert)
+    if err != nil {
+        return "", err
+    }
+
+    if dryRun {
+        runs, err := api.Client.LoadDryRuns(config.DryRunFilter{ GroupId: group_id })
+        if err == nil && len(runs) == 0 {
+            err = api.saveDryRun(receiver, group_id, is, issueADF, alert)
+        }
+        return "", err
+    }
+
+    is, err = createIssue(receiver.ApiUrl, tp, is, issueADF)
+    if err != nil {
+        return "", err
+    }
+
+    tk := config.Issue{
+        GroupId:    group_id,
+        Receiver:   receiver.Name,
+        Template:   tmpl.Name(),
+        IssueId:    is.ID,
+        IssueKey:   is.Key,
+        IssueSelf:  is.Self,
+    }
+    if err := api.Client.SaveIssue(tk); err != nil {
+        return "", err
+    }
+
+    log.Printf("[info] create parent issue: %s", is.Key)
+    api.saveEvent(config.EventCreated, group_id, is.Key, "parent " + link_key, alert)
+
+    return is.Key, nil
+}
@@ -0,0 +1,128 @@
+package v1
+
+import (
+    "fmt"
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "github.com/andygrunwald/go-jira"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+// linkDb keeps the stored issues by group id
+type linkDb struct {
+    fakeDb
+    issues       map[string]config.Issue
+}
+
+func (f *linkDb) LoadIssue(group_id string) (config.Issue, bool, error) {
+    i, ok := f.issues[group_id]
+    return i, ok, nil
+}
+
+func (f *linkDb) SaveIssue(issue config.Issue) error {
+    f.issues[issue.GroupId] = issue
+    return nil
+}
+
+func TestLinkKey(t *testing.T) {
+    receiver := &config.Receiver{ LinkBy: []string{"cluster", "service"} }
+
+    tests := []struct {
+        name     string
+        labels   map[string]interface{}
+        want     string
+    }{
+        {"all labels", map[string]interface{}{ "cluster": "eu", "service": "api", "instance": "web-01" }, "cluster=eu,service=api"},
+        {"missing label", map[string]interface{}{ "cluster": "eu" }, ""},
+        {"empty label", map[string]interface{}{ "cluster": "eu", "service": "" }, ""},
+        {"no labels", nil, ""},
+    }
+    for _, tt := range tests {
+        if got := linkKey(receiver, map[string]interface{}{ "labels": tt.labels }); got != tt.want {
+            t.Errorf("%s: linkKey() = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+
+    if got := linkKey(&config.Receiver{}, map[string]interface{}{ "labels": map[string]interface{}{ "cluster": "eu" } }); got != "" {
+        t.Errorf("linkKey() without link_by = %q, want empty", got)
+    }
+}
+
+func TestLatestOpen(t *testing.T) {
+    cfg := &config.Config{ Defaults: &config.Defaults{ ResolveState: []string{"5"} } }
+    api := &Api{ Config: cfg }
+
+    issues := []config.Issue{
+        { IssueKey: "TEST-1", LinkKey: "service=api", StatusId: "1", Created: 100 },
+        { IssueKey: "TEST-2", LinkKey: "service=api", StatusId: "1", Created: 300 },
+        { IssueKey: "TEST-3", LinkKey: "service=api", StatusId: "5", Created: 400 },
+        { IssueKey: "TEST-4", LinkKey: "service=db", StatusId: "1", Created: 500 },
+        { IssueKey: "TEST-5", LinkKey: "service=api", StatusId: "1", Created: 200 },
+    }
+
+    if latest, ok := api.latestOpen(issues, "service=api"); !ok || latest.IssueKey != "TEST-2" {
+        t.Errorf("latestOpen(service=api) = %s, %v, want the newest unresolved TEST-2", latest.IssueKey, ok)
+    }
+    if latest, ok := api.latestOpen(issues, "service=web"); ok {
+        t.Errorf("latestOpen(service=web) = %s, want none", latest.IssueKey)
+    }
+    if latest, ok := api.latestOpen(issues[2:3], "service=api"); ok {
+        t.Errorf("latestOpen() of a resolved issue = %s, want none", latest.IssueKey)
+    }
+}
+
+func TestParentIssue(t *testing.T) {
+    created := 0
+    jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != "POST" || r.URL.Path != "/rest/api/2/issue" {
+            w.WriteHeader(404)
+            return
+        }
+        created++
+        w.WriteHeader(201)
+        fmt.Fprintf(w, `{"id":"%d","key":"TEST-%d","self":"http://jira/rest/api/2/issue/%d"}`, created, created, created)
+    }))
+    defer jiraServer.Close()
+
+    receiver := &config.Receiver{
+        Name:       "jira",
+        ApiUrl:     jiraServer.URL,
+        Project:    jira.Project{ Key: "TEST" },
+        LinkBy:     []string{"service"},
+        ParentMode: &config.ParentMode{ IssueType: jira.IssueType{ Name: "Task" }, Summary: "Service {{ .labels.service }}" },
+    }
+    cfg := &config.Config{
+        Defaults:   &config.Defaults{ ResolveState: []string{"5"} },
+        Receivers:  []*config.Receiver{receiver},
+    }
+    client := &linkDb{ issues: map[string]config.Issue{} }
+    api := &Api{ Client: client, Config: cfg, recorded: newEventRecorder() }
+    tmpl := loadTestTemplate(t)
+    alert := map[string]interface{}{ "status": "firing", "labels": map[string]interface{}{ "service": "api" } }
+
+    // The open parent is reused by the following alerts of the link key
+    for i := 0; i < 2; i++ {
+        key, err := api.parentIssue(jira.BasicAuthTransport{}, receiver, tmpl, "service=api", alert, false)
+        if err != nil || key != "TEST-1" {
+            t.Fatalf("parentIssue() call %d = %q, %v, want TEST-1", i + 1, key, err)
+        }
+    }
+    if created != 1 {
+        t.Errorf("%d parent issues created, want 1", created)
+    }
+
+    // A resolved parent is replaced
+    group_id := parentGroup("jira", "service=api")
+    parent := client.issues[group_id]
+    parent.StatusId = "5"
+    client.issues[group_id] = parent
+
+    key, err := api.parentIssue(jira.BasicAuthTransport{}, receiver, tmpl, "service=api", alert, false)
+    if err != nil || key != "TEST-2" || created != 2 {
+        t.Errorf("parentIssue() after the parent was resolved = %q, %v, want the new parent TEST-2", key, err)
+    }
+    if len(client.events) != 2 || client.events[0].Event != config.EventCreated {
+        t.Errorf("events %+v, want one created event per parent", client.events)
+    }
+}
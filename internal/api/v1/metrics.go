@@ -0,0 +1,83 @@
+package v1
+
+import (
+    "fmt"
+    "sort"
+    "bytes"
+    "strings"
+    "net/http"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeMetric writes the help and type lines of a metric in the Prometheus text format
+func writeMetric(buf *bytes.Buffer, name, kind, help string) {
+    fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeSample writes a sample of a metric with a single label
+func writeSample(buf *bytes.Buffer, name, label, value string, v interface{}) {
+    fmt.Fprintf(buf, "%s{%s=\"%s\"} %v\n", name, label, labelEscaper.Replace(value), v)
+}
+
+// ApiMetrics returns the throttling, overflow, storm and silence counters in the Prometheus text format
+func (api *Api) ApiMetrics(w http.ResponseWriter, r *http.Request) {
+    if r.Method != "GET" {
+        writeError(w, 405, fmt.Errorf("method %s not allowed", r.Method))
+        return
+    }
+
+    cfg, _ := api.state()
+    var buf bytes.Buffer
+
+    writeMetric(&buf, "jiramanager_queue_length", "gauge", "Alerts waiting in the receiver queue.")
+    for _, receiver := range cfg.Receivers {
+        ch, _ := getChannel(receiver.Name)
+        writeSample(&buf, "jiramanager_queue_length", "receiver", receiver.Name, len(ch))
+    }
+
+    writeMetric(&buf, "jiramanager_storm_active", "gauge", "Whether an alert storm is detected for the receiver.")
+    for _, receiver := range cfg.Receivers {
+        active := 0
+        if api.storms.isActive(receiver.Name) {
+            active = 1
+        }
+        writeSample(&buf, "jiramanager_storm_active", "receiver", receiver.Name, active)
+    }
+
+    writeMetric(&buf, "jiramanager_silenced_alerts_total", "counter", "Alerts suppressed by silences since the start.")
+    for _, receiver := range cfg.Receivers {
+        writeSample(&buf, "jiramanager_silenced_alerts_total", "receiver", receiver.Name, api.silenced.receiver(receiver.Name))
+    }
+
+    api.limits.Lock()
+    defer api.limits.Unlock()
+
+    writeMetric(&buf, "jiramanager_pending_alerts", "gauge", "Alerts held back by the overflow, mute time intervals and flapping detection.")
+    for _, receiver := range cfg.Receivers {
+        pending := len(api.limits.queued[receiver.Name]) + len(api.limits.aggregated[receiver.Name]) + len(api.limits.deferred[receiver.Name]) + api.flaps.held(receiver.Name)
+        writeSample(&buf, "jiramanager_pending_alerts", "receiver", receiver.Name, pending)
+    }
+
+    writeMetric(&buf, "jiramanager_overflow_alerts_total", "counter", "Throttled alerts by receiver and overflow behaviour.")
+    for _, receiver := range cfg.Receivers {
+        for _, overflow := range []string{config.OverflowDrop, config.OverflowQueue, config.OverflowAggregate} {
+            fmt.Fprintf(&buf, "jiramanager_overflow_alerts_total{receiver=\"%s\",overflow=\"%s\"} %d\n",
+                labelEscaper.Replace(receiver.Name), overflow, api.limits.overflows[receiver.Name][overflow])
+        }
+    }
+
+    writeMetric(&buf, "jiramanager_throttled_alerts_total", "counter", "Alerts throttled by a rate limit.")
+    var scopes []string
+    for scope := range api.limits.throttled {
+        scopes = append(scopes, scope)
+    }
+    sort.Strings(scopes)
+    for _, scope := range scopes {
+        writeSample(&buf, "jiramanager_throttled_alerts_total", "scope", scope, api.limits.throttled[scope])
+    }
+
+    w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+    w.Write(buf.Bytes())
+}
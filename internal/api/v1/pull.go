@@ -0,0 +1,266 @@
+package v1
+
+import (
+    "fmt"
+    "log"
+    "sync"
+    "time"
+    "strings"
+    "net/url"
+    "net/http"
+    "io/ioutil"
+    "encoding/json"
+    "github.com/ltkh/jiramanager/internal/config"
+    "github.com/ltkh/jiramanager/internal/template"
+)
+
+// pullSources keeps the alert sources that are polled
+type pullSources struct {
+    sync.Mutex
+    started      map[string]bool
+}
+
+func newPullSources() *pullSources {
+    return &pullSources{ started: make(map[string]bool) }
+}
+
+type amAlert struct {
+    Labels       map[string]interface{}       `json:"labels"`
+    Annotations  map[string]interface{}       `json:"annotations"`
+    StartsAt     string                       `json:"startsAt"`
+    EndsAt       string                       `json:"endsAt"`
+    GeneratorURL string                       `json:"generatorURL"`
+    Fingerprint  string                       `json:"fingerprint"`
+}
+
+// getAlerts requests the alerts of the source
+func getAlerts(src *config.AlertSource, path string, query url.Values, v interface{}) error {
+    u := strings.TrimRight(src.Url, "/") + path
+    if len(query) > 0 {
+        u = u + "?" + query.Encode()
+    }
+
+    req, err := http.NewRequest("GET", u, nil)
+    if err != nil {
+        return err
+    }
+    if src.User != "" {
+        req.SetBasicAuth(src.User, src.Password)
+    }
+
+    client := &http.Client{ Timeout: src.Timeout }
+    resp, err := client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    body, err := ioutil.ReadAll(resp.Body)
+    if err != nil {
+        return err
+    }
+    if resp.StatusCode != 200 {
+        return fmt.Errorf("GET %s: %s %s", path, resp.Status, strings.TrimSpace(string(body)))
+    }
+
+    return json.Unmarshal(body, v)
+}
+
+// fetchAlerts returns the firing alerts of the source in the webhook format
+func fetchAlerts(src *config.AlertSource) ([]map[string]interface{}, error) {
+    var alerts []map[string]interface{}
+
+    switch src.Type {
+        case config.SourcePrometheus:
+            var data template.Data
+            if err := getAlerts(src, "/api/v1/alerts", nil, &data); err != nil {
+                return nil, err
+            }
+            if data.Status != "success" {
+                return nil, fmt.Errorf("prometheus alerts: %s", data.Error)
+            }
+            for _, a := range data.Data.Alerts {
+                // Pending alerts did not fire yet
+                if a["state"] != "firing" {
+                    continue
+                }
+                alerts = append(alerts, map[string]interface{}{
+                    "status":      "firing",
+                    "labels":      a["labels"],
+                    "annotations": a["annotations"],
+                    "startsAt":    a["activeAt"],
+                })
+            }
+
+        case config.SourceAlertmanager:
+            query := url.Values{}
+            query.Set("active", "true")
+            query.Set("silenced", "false")
+            query.Set("inhibited", "false")
+            for _, filter := range src.Filters {
+                query.Add("filter", filter)
+            }
+            var data []amAlert
+            if err := getAlerts(src, "/api/v2/alerts", query, &data); err != nil {
+                return nil, err
+            }
+            for _, a := range data {
+                alerts = append(alerts, map[string]interface{}{
+                    "status":       "firing",
+                    "labels":       a.Labels,
+                    "annotations":  a.Annotations,
+                    "startsAt":     a.StartsAt,
+                    "endsAt":       "0001-01-01T00:00:00Z",
+                    "generatorURL": a.GeneratorURL,
+                    "fingerprint":  a.Fingerprint,
+                })
+            }
+    }
+
+    // Prometheus has no server side filters and Alertmanager filters are applied to all alerts
+    matchers := compileMatchers(src.Matchers)
+    var result []map[string]interface{}
+    for _, alert := range alerts {
+        if matchLabels(matchers, alert) {
+            result = append(result, alert)
+        }
+    }
+    return result, nil
+}
+
+// startSources starts polling the configured alert sources that are not polled yet
+func (api *Api) startSources() {
+    cfg, _ := api.state()
+
+    api.pulls.Lock()
+    defer api.pulls.Unlock()
+    for _, src := range cfg.AlertSources {
+        if api.pulls.started[src.Name] {
+            continue
+        }
+        api.pulls.started[src.Name] = true
+        go api.pollSource(src.Name)
+    }
+}
+
+// stopSource forgets the source removed from the configuration, it returns false
+// when the source was added again meanwhile
+func (api *Api) stopSource(name string) bool {
+    api.pulls.Lock()
+    defer api.pulls.Unlock()
+    if cfg, _ := api.state(); cfg.GetAlertSource(name) != nil {
+        return false
+    }
+    delete(api.pulls.started, name)
+    return true
+}
+
+// storedAlerts returns the alerts of the stored issues of the source receiver matching its filters
+func (api *Api) storedAlerts(src *config.AlertSource) (map[string]map[string]interface{}, error) {
+    issues, err := api.Client.LoadIssues()
+    if err != nil {
+        return nil, err
+    }
+
+    matchers := compileMatchers(src.Matchers)
+    alerts := make(map[string]map[string]interface{})
+    for _, i := range issues {
+        if i.Receiver != src.Receiver || len(i.Labels) == 0 {
+            continue
+        }
+        labels := make(map[string]interface{}, len(i.Labels))
+        for name, value := range i.Labels {
+            labels[name] = value
+        }
+        alert := map[string]interface{}{ "status": "firing", "labels": labels }
+        if matchLabels(matchers, alert) {
+            alerts[i.GroupId] = alert
+        }
+    }
+    return alerts, nil
+}
+
+// pollSource sends the new alerts of the source to its receiver and sends the firing alerts without
+// issue again every repeat_interval, alerts that disappear from the source are sent as resolved
+func (api *Api) pollSource(name string) {
+    var seen map[string]map[string]interface{}
+    sent := make(map[string]time.Time)
+
+    for {
+
+        // The source settings are looked up on every pass, so a reload applies to the next poll
+        cfg, _ := api.state()
+        src := cfg.GetAlertSource(name)
+        if src == nil {
+            if api.stopSource(name) {
+                log.Printf("[info] alert source %s removed, polling stopped", name)
+                return
+            }
+            continue
+        }
+
+        ch, ok := getChannel(src.Receiver)
+        if !ok {
+            time.Sleep(5 * time.Second)
+            continue
+        }
+
+        // Alerts of the stored issues that resolved while the service was down are sent as resolved
+        if seen == nil {
+            stored, err := api.storedAlerts(src)
+            if err != nil {
+                log.Printf("[error] load issues of alert source %s: %v", name, err)
+                time.Sleep(src.Interval)
+                continue
+            }
+            seen = stored
+        }
+
+        alerts, err := fetchAlerts(src)
+        if err != nil {
+            log.Printf("[error] poll alert source %s: %v", name, err)
+            time.Sleep(src.Interval)
+            continue
+        }
+
+        now := time.Now()
+        current := make(map[string]map[string]interface{})
+        for _, alert := range alerts {
+            labels, err := json.Marshal(alert["labels"])
+            if err != nil {
+                continue
+            }
+            group_id := getHash(string(labels))
+            current[group_id] = alert
+            // Known alerts are sent again every repeat_interval while they have no issue
+            if _, ok := seen[group_id]; ok {
+                if now.Sub(sent[group_id]) < src.RepeatInterval {
+                    continue
+                }
+                sent[group_id] = now
+                if _, found, err := api.Client.LoadIssue(group_id); err == nil && found {
+                    continue
+                }
+            }
+            ch <- alert
+            sent[group_id] = now
+        }
+
+        for group_id, alert := range seen {
+            if _, ok := current[group_id]; ok {
+                continue
+            }
+            delete(sent, group_id)
+            resolved := make(map[string]interface{}, len(alert))
+            for k, v := range alert {
+                resolved[k] = v
+            }
+            resolved["status"] = "resolved"
+            resolved["endsAt"] = now.UTC().Format(time.RFC3339)
+            ch <- resolved
+        }
+
+        seen = current
+        time.Sleep(src.Interval)
+    }
+}
@@ -0,0 +1,103 @@
+package v1
+
+import (
+    "time"
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+// pullDb serves the stored issues, alerts of the issues are found by their group id
+type pullDb struct {
+    fakeDb
+    issues       []config.Issue
+}
+
+func (f *pullDb) LoadIssues() ([]config.Issue, error) {
+    return f.issues, nil
+}
+
+func (f *pullDb) LoadIssue(group_id string) (config.Issue, bool, error) {
+    for _, i := range f.issues {
+        if i.GroupId == group_id {
+            return i, true, nil
+        }
+    }
+    return config.Issue{}, false, nil
+}
+
+func TestPollSource(t *testing.T) {
+    prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte(`{"status":"success","data":{"alerts":[
+            {"state":"firing","labels":{"alertname":"new"}},
+            {"state":"firing","labels":{"alertname":"known"}},
+            {"state":"pending","labels":{"alertname":"pending"}}
+        ]}}`))
+    }))
+    defer prometheus.Close()
+
+    ch := make(chan map[string]interface{}, 100)
+    req_mtx.Lock()
+    req_chan["pull"] = ch
+    req_mtx.Unlock()
+    defer func() {
+        req_mtx.Lock()
+        delete(req_chan, "pull")
+        req_mtx.Unlock()
+    }()
+
+    src := &config.AlertSource{
+        Name:           "prometheus",
+        Type:           config.SourcePrometheus,
+        Url:            prometheus.URL,
+        Receiver:       "pull",
+        Interval:       20 * time.Millisecond,
+        RepeatInterval: 50 * time.Millisecond,
+        Timeout:        time.Second,
+    }
+    cfg := &config.Config{ AlertSources: []*config.AlertSource{src} }
+    client := &pullDb{ issues: []config.Issue{
+        { GroupId: getHash(`{"alertname":"known"}`), Receiver: "pull", Labels: map[string]string{ "alertname": "known" } },
+        { GroupId: getHash(`{"alertname":"gone"}`), Receiver: "pull", Labels: map[string]string{ "alertname": "gone" } },
+        { GroupId: getHash(`{"alertname":"other"}`), Receiver: "other", Labels: map[string]string{ "alertname": "other" } },
+    }}
+    api := &Api{ Client: client, Config: cfg, pulls: newPullSources() }
+    api.startSources()
+
+    received := map[string]int{}
+    deadline := time.After(5 * time.Second)
+    for received["firing new"] < 3 {
+        select {
+            case alert := <-ch:
+                labels, _ := alert["labels"].(map[string]interface{})
+                received[alert["status"].(string) + " " + labels["alertname"].(string)]++
+            case <-deadline:
+                t.Fatalf("alerts received %v, want the alert without issue sent again", received)
+        }
+    }
+    if received["resolved gone"] != 1 {
+        t.Errorf("alerts received %v, want the alert of the stored issue resolved once", received)
+    }
+    if len(received) != 2 {
+        t.Errorf("alerts received %v, want only the new and the resolved alert", received)
+    }
+
+    // The goroutine of a removed source exits
+    api.mtx.Lock()
+    api.Config = &config.Config{}
+    api.mtx.Unlock()
+    for {
+        api.pulls.Lock()
+        started := api.pulls.started["prometheus"]
+        api.pulls.Unlock()
+        if !started {
+            break
+        }
+        select {
+            case <-deadline:
+                t.Fatal("polling of the removed source did not stop")
+            case <-time.After(10 * time.Millisecond):
+        }
+    }
+}
@@ -0,0 +1,119 @@
+package v1
+
+import (
+    "fmt"
+    "sync"
+    "time"
+    "net/http"
+    "github.com/andygrunwald/go-jira"
+)
+
+type Component struct {
+    Status       string                       `json:"status"`
+    Error        string                       `json:"error,omitempty"`
+}
+
+type jiraCheck struct {
+    checked      time.Time
+    err          error
+}
+
+type readiness struct {
+    sync.Mutex
+    updated      time.Time
+    jira         map[string]jiraCheck
+}
+
+func (rd *readiness) setUpdated(t time.Time) {
+    rd.Lock()
+    defer rd.Unlock()
+    rd.updated = t
+}
+
+func (rd *readiness) getUpdated() time.Time {
+    rd.Lock()
+    defer rd.Unlock()
+    return rd.updated
+}
+
+func newComponent(err error) Component {
+    if err != nil {
+        return Component{ Status: "error", Error: err.Error() }
+    }
+    return Component{ Status: "success" }
+}
+
+// checkJira verifies the credentials against /rest/api/2/myself, results are cached for jira_cache_ttl
+func (api *Api) checkJira(base string) error {
+    api.ready.Lock()
+    check, ok := api.ready.jira[base]
+    api.ready.Unlock()
+
+    cfg, _ := api.state()
+    if ok && time.Since(check.checked) < cfg.Readiness.JiraCacheTTL {
+        return check.err
+    }
+
+    tp := jira.BasicAuthTransport{
+        Username: cfg.Defaults.User,
+        Password: cfg.Defaults.Password,
+    }
+
+    // The check runs within the /-/ready request, a hanging Jira must not block it
+    client := tp.Client()
+    client.Timeout = cfg.Readiness.JiraTimeout
+
+    jiraClient, err := jira.NewClient(client, base)
+    if err == nil {
+        _, _, err = jiraClient.User.GetSelf()
+    }
+
+    api.ready.Lock()
+    if api.ready.jira == nil {
+        api.ready.jira = make(map[string]jiraCheck)
+    }
+    api.ready.jira[base] = jiraCheck{ checked: time.Now(), err: err }
+    api.ready.Unlock()
+
+    return err
+}
+
+// ApiReady reports the status of the database, Jira, receiver queues and the status updates
+func (api *Api) ApiReady(w http.ResponseWriter, r *http.Request) {
+    components := map[string]Component{}
+
+    components["db"] = newComponent(api.Client.Ping())
+
+    cfg, _ := api.state()
+    for _, receiver := range cfg.Receivers {
+        name := "jira " + receiver.ApiUrl
+        if _, ok := components[name]; !ok {
+            components[name] = newComponent(api.checkJira(receiver.ApiUrl))
+        }
+
+        var err error
+        ch, _ := getChannel(receiver.Name)
+        if size := len(ch); size >= cfg.Readiness.QueueThreshold {
+            err = fmt.Errorf("queue backlog %d exceeds threshold %d", size, cfg.Readiness.QueueThreshold)
+        }
+        components["queue " + receiver.Name] = newComponent(err)
+    }
+
+    var err error
+    if updated := api.ready.getUpdated(); updated.IsZero() {
+        err = fmt.Errorf("status update has not completed yet")
+    } else if age := time.Since(updated); age > cfg.Readiness.UpdateMaxAge {
+        err = fmt.Errorf("last status update was %s ago", age.Truncate(time.Second))
+    }
+    components["update_status"] = newComponent(err)
+
+    for _, c := range components {
+        if c.Status != "success" {
+            w.WriteHeader(503)
+            w.Write(encodeResp(&Resp{Status:"error", Error:"not ready", Data:components}))
+            return
+        }
+    }
+
+    w.Write(encodeResp(&Resp{Status:"success", Data:components}))
+}
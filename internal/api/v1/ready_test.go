@@ -0,0 +1,79 @@
+package v1
+
+import (
+    "time"
+    "errors"
+    "testing"
+    "net/http"
+    "encoding/json"
+    "net/http/httptest"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+// readyDb fails Ping with pingErr
+type readyDb struct {
+    fakeDb
+    pingErr      error
+}
+
+func (f *readyDb) Ping() error {
+    return f.pingErr
+}
+
+func TestApiReady(t *testing.T) {
+    jiraUp := true
+    jira := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/rest/api/2/myself" || !jiraUp {
+            w.WriteHeader(401)
+            return
+        }
+        w.Write([]byte(`{"name":"jiramanager"}`))
+    }))
+    defer jira.Close()
+
+    cfg := &config.Config{
+        Defaults:   &config.Defaults{ User: "jiramanager", Password: "secret" },
+        Readiness:  &config.Readiness{ QueueThreshold: 10, UpdateMaxAge: time.Minute, JiraTimeout: time.Second },
+        Receivers:  []*config.Receiver{ &config.Receiver{ Name: "ready", ApiUrl: jira.URL } },
+    }
+
+    tests := []struct {
+        name     string
+        pingErr  error
+        jiraUp   bool
+        updated  time.Time
+        code     int
+        failed   string
+    }{
+        {"ready", nil, true, time.Now(), 200, ""},
+        {"database down", errors.New("database is locked"), true, time.Now(), 503, "db"},
+        {"jira down", nil, false, time.Now(), 503, "jira " + jira.URL},
+        {"no status update yet", nil, true, time.Time{}, 503, "update_status"},
+        {"status update too old", nil, true, time.Now().Add(-time.Hour), 503, "update_status"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            jiraUp = tt.jiraUp
+            api := &Api{ Client: &readyDb{ pingErr: tt.pingErr }, Config: cfg }
+            api.ready.setUpdated(tt.updated)
+
+            w := httptest.NewRecorder()
+            api.ApiReady(w, httptest.NewRequest("GET", "/-/ready", nil))
+            if w.Code != tt.code {
+                t.Fatalf("ApiReady() = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+            }
+
+            var resp struct {
+                Data     map[string]Component
+            }
+            if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+                t.Fatal(err)
+            }
+            for name, c := range resp.Data {
+                if failed := c.Status != "success"; failed != (name == tt.failed) {
+                    t.Errorf("component %s = %+v", name, c)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,295 @@
+package v1
+
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+    "io/ioutil"
+    "encoding/json"
+    "github.com/andygrunwald/go-jira"
+    "github.com/ltkh/jiramanager/internal/config"
+    "github.com/ltkh/jiramanager/internal/template"
+)
+
+// renderValue executes the templates in all strings of a field value
+func renderValue(tmpl *template.Template, value interface{}, alert map[string]interface{}) (interface{}, error) {
+    switch v := value.(type) {
+        case string:
+            return tmpl.Execute(v, alert)
+        case []interface{}:
+            result := make([]interface{}, len(v))
+            for i, item := range v {
+                r, err := renderValue(tmpl, item, alert)
+                if err != nil {
+                    return nil, err
+                }
+                result[i] = r
+            }
+            return result, nil
+        case map[interface{}]interface{}:
+            result := make(map[string]interface{}, len(v))
+            for key, item := range v {
+                r, err := renderValue(tmpl, item, alert)
+                if err != nil {
+                    return nil, err
+                }
+                result[fmt.Sprintf("%v", key)] = r
+            }
+            return result, nil
+        case map[string]interface{}:
+            result := make(map[string]interface{}, len(v))
+            for key, item := range v {
+                r, err := renderValue(tmpl, item, alert)
+                if err != nil {
+                    return nil, err
+                }
+                result[key] = r
+            }
+            return result, nil
+    }
+    return value, nil
+}
+
+// newIssue returns an issue with the project, type and components of the receiver,
+// and the priority when the receiver sends the fields
+func newIssue(receiver *config.Receiver, summary, desc string) *jira.Issue {
+    is := &jira.Issue{
+        Fields: &jira.IssueFields{
+            Project:     receiver.Project,
+            Type:        receiver.IssueType,
+            Summary:     summary,
+            Description: desc,
+        },
+    }
+
+    if receiver.Sends() && (receiver.Priority.ID != "" || receiver.Priority.Name != "") {
+        priority := receiver.Priority
+        is.Fields.Priority = &priority
+    }
+
+    for i := range receiver.Components {
+        component := receiver.Components[i]
+        is.Fields.Components = append(is.Fields.Components, &component)
+    }
+
+    return is
+}
+
+// RenderIssue runs the receiver pipeline for the alert and returns the Jira issue to create,
+// the ADF description is returned separately when the receiver uses description_format adf
+func RenderIssue(receiver *config.Receiver, tmpl *template.Template, alert map[string]interface{}) (*jira.Issue, map[string]interface{}, error) {
+    issueSummary, err := tmpl.Execute(receiver.Summary, alert)
+    if err != nil {
+        return nil, nil, fmt.Errorf("summary: %v", err)
+    }
+
+    issueDesc, err := tmpl.Execute(receiver.Description, alert)
+    if err != nil {
+        return nil, nil, fmt.Errorf("description: %v", err)
+    }
+
+    var issueADF map[string]interface{}
+    switch receiver.DescriptionFormat {
+        case template.FormatWiki:
+            issueDesc = template.MarkdownToWiki(issueDesc)
+        case template.FormatADF:
+            issueADF = template.MarkdownToADF(issueDesc)
+    }
+
+    is := newIssue(receiver, issueSummary, issueDesc)
+
+    // Jira rejects fields missing from the create screen, so they are only sent on request
+    if !receiver.Sends() {
+        return is, issueADF, nil
+    }
+
+    for _, label := range receiver.Labels {
+        value, err := tmpl.Execute(label, alert)
+        if err != nil {
+            return nil, nil, fmt.Errorf("labels: %v", err)
+        }
+        if value != "" {
+            is.Fields.Labels = append(is.Fields.Labels, value)
+        }
+    }
+
+    if len(receiver.Fields) > 0 {
+        is.Fields.Unknowns = map[string]interface{}{}
+        for key, value := range receiver.Fields {
+            r, err := renderValue(tmpl, value, alert)
+            if err != nil {
+                return nil, nil, fmt.Errorf("field %s: %v", key, err)
+            }
+            is.Fields.Unknowns[key] = r
+        }
+    }
+
+    return is, issueADF, nil
+}
+
+// RenderParent renders the parent issue of the receiver parent_mode for the alert
+func RenderParent(receiver *config.Receiver, tmpl *template.Template, alert map[string]interface{}) (*jira.Issue, map[string]interface{}, error) {
+    summary, err := tmpl.Execute(receiver.ParentMode.Summary, alert)
+    if err != nil {
+        return nil, nil, fmt.Errorf("parent_mode summary: %v", err)
+    }
+
+    desc, err := tmpl.Execute(receiver.ParentMode.Description, alert)
+    if err != nil {
+        return nil, nil, fmt.Errorf("parent_mode description: %v", err)
+    }
+
+    var issueADF map[string]interface{}
+    switch receiver.DescriptionFormat {
+        case template.FormatWiki:
+            desc = template.MarkdownToWiki(desc)
+        case template.FormatADF:
+            issueADF = template.MarkdownToADF(desc)
+    }
+
+    is := newIssue(receiver, summary, desc)
+    is.Fields.Type = receiver.ParentMode.IssueType
+
+    return is, issueADF, nil
+}
+
+// FallbackIssue returns a minimal issue for an alert the receiver templates failed to render,
+// with the fallback summary and the raw labels, so the alert is not dropped
+func FallbackIssue(receiver *config.Receiver, tmpl *template.Template, alert map[string]interface{}, cause error) (*jira.Issue, map[string]interface{}) {
+    labels, _ := json.Marshal(alert["labels"])
+
+    opts := tmpl.Options()
+    opts.Strict = false
+    summary, err := tmpl.WithOptions(opts).Execute(receiver.FallbackSummary, alert)
+    if err != nil || strings.TrimSpace(summary) == "" {
+        summary = fmt.Sprintf("Alert %s", labels)
+    }
+    if runes := []rune(summary); len(runes) > 255 {
+        summary = string(runes[:252]) + "..."
+    }
+
+    desc := fmt.Sprintf("Failed to render the issue templates of receiver %s: %v\n\nLabels:\n\n    %s", receiver.Name, cause, labels)
+    if annotations, err := json.Marshal(alert["annotations"]); err == nil && alert["annotations"] != nil {
+        desc = desc + fmt.Sprintf("\n\nAnnotations:\n\n    %s", annotations)
+    }
+
+    is := newIssue(receiver, summary, desc)
+
+    var issueADF map[string]interface{}
+    if receiver.DescriptionFormat == template.FormatADF {
+        issueADF = map[string]interface{}{
+            "version": 1,
+            "type":    "doc",
+            "content": []interface{}{
+                map[string]interface{}{
+                    "type":    "codeBlock",
+                    "content": []interface{}{map[string]interface{}{"type": "text", "text": desc}},
+                },
+            },
+        }
+    }
+
+    return is, issueADF
+}
+
+// issuePayload returns the request body sent to Jira to create the issue
+func issuePayload(is *jira.Issue, adf map[string]interface{}) (map[string]interface{}, error) {
+    jsn, err := json.Marshal(is)
+    if err != nil {
+        return nil, err
+    }
+
+    var payload map[string]interface{}
+    if err := json.Unmarshal(jsn, &payload); err != nil {
+        return nil, err
+    }
+    if adf != nil {
+        if fields, ok := payload["fields"].(map[string]interface{}); ok {
+            fields["description"] = adf
+        }
+    }
+
+    return payload, nil
+}
+
+// Alerts returns the alerts of a webhook payload, or the payload itself when it is a single alert
+func Alerts(body []byte) (string, []map[string]interface{}, error) {
+    var data Data
+    if err := json.Unmarshal(body, &data); err != nil {
+        return "", nil, err
+    }
+    if data.Alerts != nil {
+        return data.Receiver, data.Alerts, nil
+    }
+
+    var alert map[string]interface{}
+    if err := json.Unmarshal(body, &alert); err != nil {
+        return "", nil, err
+    }
+    return "", []map[string]interface{}{alert}, nil
+}
+
+type Preview struct {
+    Receiver     string                       `json:"receiver"`
+    Issue        map[string]interface{}       `json:"issue,omitempty"`
+    Error        string                       `json:"error,omitempty"`
+}
+
+// RenderPreview renders the issues the receiver would create for the alerts, without creating them
+func RenderPreview(cfg *config.Config, templates map[string]*template.Template, name string, alerts []map[string]interface{}) ([]Preview, error) {
+    receiver := cfg.GetReceiver(name)
+    if receiver == nil {
+        return nil, fmt.Errorf("unknown receiver %q", name)
+    }
+
+    result := []Preview{}
+    for _, alert := range alerts {
+        preview := Preview{ Receiver: receiver.Name }
+
+        is, adf, err := RenderIssue(receiver, templates[receiver.Name], alert)
+        if err == nil {
+            preview.Issue, err = issuePayload(is, adf)
+        }
+        if err != nil {
+            preview.Error = err.Error()
+        }
+
+        result = append(result, preview)
+    }
+
+    return result, nil
+}
+
+// ApiPreview renders the Jira issues for a webhook payload without creating them
+func (api *Api) ApiPreview(w http.ResponseWriter, r *http.Request) {
+    if r.Method != "POST" {
+        writeError(w, 405, fmt.Errorf("method %s not allowed", r.Method))
+        return
+    }
+
+    body, err := ioutil.ReadAll(r.Body)
+    if err != nil {
+        log.Printf("[error] %v - %s", err, r.URL.Path)
+        writeError(w, 400, err)
+        return
+    }
+
+    receiver, alerts, err := Alerts(body)
+    if err != nil {
+        writeError(w, 400, err)
+        return
+    }
+    if name := r.URL.Query().Get("receiver"); name != "" {
+        receiver = name
+    }
+
+    cfg, templates := api.state()
+    previews, err := RenderPreview(cfg, templates, receiver, alerts)
+    if err != nil {
+        writeError(w, 400, err)
+        return
+    }
+
+    w.Write(encodeResp(&Resp{Status:"success", Data:previews}))
+}
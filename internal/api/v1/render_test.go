@@ -0,0 +1,125 @@
+package v1
+
+import (
+    "strings"
+    "testing"
+    "github.com/andygrunwald/go-jira"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+func TestRenderIssueSendFields(t *testing.T) {
+    tmpl := loadTestTemplate(t)
+    alert := map[string]interface{}{
+        "status": "firing",
+        "labels": map[string]interface{}{ "alertname": "HostDown", "team": "ops" },
+    }
+
+    for _, send := range []bool{false, true} {
+        receiver := &config.Receiver{
+            Name:       "jira",
+            Project:    jira.Project{ Key: "TEST" },
+            IssueType:  jira.IssueType{ Name: "Bug" },
+            Summary:    "{{ .labels.alertname }}",
+            Priority:   jira.Priority{ Name: "High" },
+            Components: []jira.Component{{ Name: "api" }, { Name: "db" }},
+            Labels:     []string{"{{ .labels.alertname }}", "{{ if .labels.missing }}missing{{ end }}"},
+            Fields:     map[string]interface{}{ "customfield_10001": map[interface{}]interface{}{ "value": "{{ .labels.team }}" } },
+            SendFields: &send,
+        }
+
+        is, _, err := RenderIssue(receiver, tmpl, alert)
+        if err != nil {
+            t.Fatalf("RenderIssue() with send_fields %v: %v", send, err)
+        }
+        payload, err := issuePayload(is, nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        fields := payload["fields"].(map[string]interface{})
+
+        if fields["summary"] != "HostDown" {
+            t.Errorf("send_fields %v: summary %v", send, fields["summary"])
+        }
+        if components, _ := fields["components"].([]interface{}); len(components) != 2 {
+            t.Errorf("send_fields %v: components %v, want api and db", send, fields["components"])
+        }
+
+        _, priority := fields["priority"]
+        _, labels := fields["labels"]
+        _, custom := fields["customfield_10001"]
+        if !send && (priority || labels || custom) {
+            t.Errorf("send_fields false: fields %v, want no priority, labels or custom fields", fields)
+        }
+        if !send {
+            continue
+        }
+        if p, _ := fields["priority"].(map[string]interface{}); p["name"] != "High" {
+            t.Errorf("send_fields true: priority %v", fields["priority"])
+        }
+        if l, _ := fields["labels"].([]interface{}); len(l) != 1 || l[0] != "HostDown" {
+            t.Errorf("send_fields true: labels %v, want the non-empty label HostDown", fields["labels"])
+        }
+        if c, _ := fields["customfield_10001"].(map[string]interface{}); c["value"] != "ops" {
+            t.Errorf("send_fields true: customfield_10001 %v", fields["customfield_10001"])
+        }
+    }
+}
+
+func TestFallbackIssue(t *testing.T) {
+    tmpl := loadTestTemplate(t)
+    opts := tmpl.Options()
+    opts.Strict = true
+    strict := tmpl.WithOptions(opts)
+
+    alert := map[string]interface{}{
+        "status":      "firing",
+        "labels":      map[string]interface{}{ "alertname": "HostDown", "instance": "web-01" },
+        "annotations": map[string]interface{}{ "summary": "Host is down" },
+    }
+    labels := `{"alertname":"HostDown","instance":"web-01"}`
+
+    tests := []struct {
+        name     string
+        fallback string
+        format   string
+        summary  string
+    }{
+        {"fallback summary", "{{ .labels.alertname }} on {{ .labels.instance }}", "", "HostDown on web-01"},
+        {"fallback summary ignores strict mode", "{{ .labels.alertname }} {{ .labels.missing }}", "", "HostDown <no value>"},
+        {"invalid fallback summary", "{{ .labels.alertname", "", "Alert " + labels},
+        {"empty fallback summary", "{{ if .labels.missing }}missing{{ end }}", "", "Alert " + labels},
+        {"adf description", "{{ .labels.alertname }}", "adf", "HostDown"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            receiver := &config.Receiver{
+                Name:              "jira",
+                Project:           jira.Project{ Key: "TEST" },
+                IssueType:         jira.IssueType{ Name: "Bug" },
+                Summary:           "{{ .labels.missing }}",
+                FallbackSummary:   tt.fallback,
+                DescriptionFormat: tt.format,
+            }
+
+            // The missing key fails the strict summary, the alert is reported with the fallback issue
+            _, _, err := RenderIssue(receiver, strict, alert)
+            if err == nil {
+                t.Fatal("RenderIssue() with a missing key in strict mode succeeded")
+            }
+            is, adf := FallbackIssue(receiver, strict, alert, err)
+
+            if is.Fields.Summary != tt.summary {
+                t.Errorf("summary %q, want %q", is.Fields.Summary, tt.summary)
+            }
+            desc := is.Fields.Description
+            for _, want := range []string{"receiver jira: summary:", "Labels:\n\n    " + labels, `Annotations:`, `"summary":"Host is down"`} {
+                if !strings.Contains(desc, want) {
+                    t.Errorf("description %q, want %q", desc, want)
+                }
+            }
+            if (adf != nil) != (tt.format == "adf") {
+                t.Errorf("adf %v with description_format %q", adf, tt.format)
+            }
+        })
+    }
+}
@@ -0,0 +1,329 @@
+package v1
+
+import (
+    "fmt"
+    "log"
+    "sync"
+    "time"
+    "regexp"
+    "strconv"
+    "strings"
+    "net/http"
+    "io/ioutil"
+    "encoding/json"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+// How long the active silences are cached when none of them ends earlier
+const silenceCacheTTL = time.Minute
+
+// silenceCounter keeps the number of alerts suppressed by every silence and receiver,
+// the counters are kept in memory only and start from zero after a restart
+type silenceCounter struct {
+    sync.Mutex
+    silences     map[int64]int64
+    receivers    map[string]int64
+}
+
+func newSilenceCounter() *silenceCounter {
+    return &silenceCounter{
+        silences:   make(map[int64]int64),
+        receivers:  make(map[string]int64),
+    }
+}
+
+func (c *silenceCounter) add(id int64, receiver string) {
+    c.Lock()
+    defer c.Unlock()
+    c.silences[id]++
+    c.receivers[receiver]++
+}
+
+func (c *silenceCounter) silence(id int64) int64 {
+    c.Lock()
+    defer c.Unlock()
+    return c.silences[id]
+}
+
+func (c *silenceCounter) receiver(name string) int64 {
+    c.Lock()
+    defer c.Unlock()
+    return c.receivers[name]
+}
+
+type SilenceRequest struct {
+    Matchers     []config.Matcher             `json:"matchers"`
+    CreatedBy    string                       `json:"created_by"`
+    Comment      string                       `json:"comment"`
+    EndsAt       string                       `json:"ends_at"`
+    Duration     string                       `json:"duration"`
+}
+
+// labelMatcher is a matcher with its compiled regular expression
+type labelMatcher struct {
+    config.Matcher
+    re           *regexp.Regexp
+}
+
+// compileMatcher compiles the regular expression of the matcher, anchored like in Alertmanager
+func compileMatcher(m config.Matcher) labelMatcher {
+    lm := labelMatcher{ Matcher: m }
+    if m.IsRegex {
+        lm.re, _ = regexp.Compile("^(?:" + m.Value + ")$")
+    }
+    return lm
+}
+
+// match reports whether the label value matches, an invalid regular expression matches no value
+func (m labelMatcher) match(value string) bool {
+    matched := value == m.Value
+    if m.IsRegex {
+        matched = m.re != nil && m.re.MatchString(value)
+    }
+    return matched == m.IsEqual
+}
+
+// matchLabel reports whether the label value matches the matcher, regular expressions are anchored
+func matchLabel(m config.Matcher, value string) bool {
+    return compileMatcher(m).match(value)
+}
+
+// matchLabels reports whether the alert labels match all compiled matchers
+func matchLabels(matchers []labelMatcher, alert map[string]interface{}) bool {
+    labels, _ := alert["labels"].(map[string]interface{})
+    for _, m := range matchers {
+        value, _ := labels[m.Name].(string)
+        if !m.match(value) {
+            return false
+        }
+    }
+    return true
+}
+
+// compileMatchers compiles the regular expressions of the matchers
+func compileMatchers(matchers []config.Matcher) []labelMatcher {
+    compiled := make([]labelMatcher, len(matchers))
+    for i, m := range matchers {
+        compiled[i] = compileMatcher(m)
+    }
+    return compiled
+}
+
+// matchSilence reports whether the alert labels match all matchers of the silence
+func matchSilence(sl config.Silence, alert map[string]interface{}) bool {
+    return matchLabels(compileMatchers(sl.Matchers), alert)
+}
+
+type activeSilence struct {
+    silence      config.Silence
+    matchers     []labelMatcher
+}
+
+// silenceCache keeps the active silences with their compiled matchers, they are loaded again
+// when a silence is created or expired, when one of them ends and after silenceCacheTTL
+type silenceCache struct {
+    sync.Mutex
+    silences     []activeSilence
+    expires      time.Time
+}
+
+func newSilenceCache() *silenceCache {
+    return &silenceCache{}
+}
+
+// invalidate makes the next lookup load the silences from the database
+func (c *silenceCache) invalidate() {
+    c.Lock()
+    defer c.Unlock()
+    c.expires = time.Time{}
+}
+
+// activeSilences returns the cached active silences, loaded from the database when they expired
+func (api *Api) activeSilences() ([]activeSilence, error) {
+    c := api.silences
+    c.Lock()
+    defer c.Unlock()
+
+    now := time.Now()
+    if now.Before(c.expires) {
+        return c.silences, nil
+    }
+
+    silences, err := api.Client.LoadSilences(config.SilenceFilter{ Active: true })
+    if err != nil {
+        return nil, err
+    }
+
+    c.silences = make([]activeSilence, 0, len(silences))
+    c.expires = now.Add(silenceCacheTTL)
+    for _, sl := range silences {
+        c.silences = append(c.silences, activeSilence{ silence: sl, matchers: compileMatchers(sl.Matchers) })
+        if ends := time.Unix(sl.EndsAt, 0); ends.Before(c.expires) {
+            c.expires = ends
+        }
+    }
+    return c.silences, nil
+}
+
+// silencedBy returns the active silence matching the alert
+func (api *Api) silencedBy(alert map[string]interface{}) (config.Silence, bool, error) {
+    silences, err := api.activeSilences()
+    if err != nil {
+        return config.Silence{}, false, err
+    }
+    for _, as := range silences {
+        if matchLabels(as.matchers, alert) {
+            return as.silence, true, nil
+        }
+    }
+    return config.Silence{}, false, nil
+}
+
+// newSilence validates the request and returns the silence to create
+func newSilence(req SilenceRequest) (config.Silence, error) {
+    sl := config.Silence{
+        Matchers:   req.Matchers,
+        CreatedBy:  req.CreatedBy,
+        Comment:    req.Comment,
+        Created:    time.Now().UTC().Unix(),
+    }
+
+    if len(req.Matchers) == 0 {
+        return sl, fmt.Errorf("at least one matcher is required")
+    }
+    for _, m := range req.Matchers {
+        if m.Name == "" {
+            return sl, fmt.Errorf("missing matcher name")
+        }
+        if m.IsRegex {
+            if _, err := regexp.Compile("^(?:" + m.Value + ")$"); err != nil {
+                return sl, fmt.Errorf("invalid regular expression of matcher %q: %v", m.Name, err)
+            }
+        }
+    }
+    if strings.TrimSpace(req.CreatedBy) == "" {
+        return sl, fmt.Errorf("missing created_by")
+    }
+    if strings.TrimSpace(req.Comment) == "" {
+        return sl, fmt.Errorf("missing comment")
+    }
+
+    switch {
+        case req.EndsAt != "" && req.Duration != "":
+            return sl, fmt.Errorf("ends_at and duration are mutually exclusive")
+        case req.Duration != "":
+            d, err := time.ParseDuration(req.Duration)
+            if err != nil {
+                return sl, fmt.Errorf("invalid duration %q", req.Duration)
+            }
+            sl.EndsAt = time.Now().Add(d).UTC().Unix()
+        default:
+            endsAt, err := parseTime(req.EndsAt)
+            if err != nil {
+                return sl, err
+            }
+            sl.EndsAt = endsAt
+    }
+    if sl.EndsAt <= time.Now().UTC().Unix() {
+        return sl, fmt.Errorf("ends_at or duration must be in the future")
+    }
+
+    return sl, nil
+}
+
+// ApiSilences lists the silences and creates new ones
+func (api *Api) ApiSilences(w http.ResponseWriter, r *http.Request) {
+    switch r.Method {
+        case "GET":
+            filter := config.SilenceFilter{ Active: r.URL.Query().Get("active") == "true" }
+            silences, err := api.Client.LoadSilences(filter)
+            if err != nil {
+                log.Printf("[error] %v - %s", err, r.URL.Path)
+                writeError(w, 500, err)
+                return
+            }
+            if silences == nil {
+                silences = []config.Silence{}
+            }
+            for i := range silences {
+                silences[i].Suppressed = api.silenced.silence(silences[i].Id)
+            }
+            w.Write(encodeResp(&Resp{Status:"success", Data:silences}))
+
+        case "POST":
+            body, err := ioutil.ReadAll(r.Body)
+            if err != nil {
+                writeError(w, 400, err)
+                return
+            }
+            var req SilenceRequest
+            if err := json.Unmarshal(body, &req); err != nil {
+                writeError(w, 400, err)
+                return
+            }
+            sl, err := newSilence(req)
+            if err != nil {
+                writeError(w, 400, err)
+                return
+            }
+            if sl.Id, err = api.Client.SaveSilence(sl); err != nil {
+                log.Printf("[error] %v - %s", err, r.URL.Path)
+                writeError(w, 500, err)
+                return
+            }
+            api.silences.invalidate()
+            log.Printf("[info] silence %d created by %s: %s", sl.Id, sl.CreatedBy, sl.Comment)
+            w.Write(encodeResp(&Resp{Status:"success", Data:sl}))
+
+        default:
+            writeError(w, 405, fmt.Errorf("method %s not allowed", r.Method))
+    }
+}
+
+// ApiSilence returns or expires a silence
+func (api *Api) ApiSilence(w http.ResponseWriter, r *http.Request) {
+    path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/silences"), "/")
+    if path == "" {
+        api.ApiSilences(w, r)
+        return
+    }
+
+    id, err := strconv.ParseInt(path, 10, 64)
+    if err != nil {
+        writeError(w, 404, fmt.Errorf("page not found"))
+        return
+    }
+
+    silences, err := api.Client.LoadSilences(config.SilenceFilter{ Id: id })
+    if err != nil {
+        log.Printf("[error] %v - %s", err, r.URL.Path)
+        writeError(w, 500, err)
+        return
+    }
+    if len(silences) == 0 {
+        writeError(w, 404, fmt.Errorf("silence %d not found", id))
+        return
+    }
+    sl := silences[0]
+
+    switch r.Method {
+        case "GET":
+        case "DELETE":
+            if err := api.Client.ExpireSilence(id); err != nil {
+                log.Printf("[error] %v - %s", err, r.URL.Path)
+                writeError(w, 500, err)
+                return
+            }
+            api.silences.invalidate()
+            if now := time.Now().UTC().Unix(); sl.EndsAt > now {
+                sl.EndsAt = now
+            }
+            log.Printf("[info] silence %d expired", id)
+        default:
+            writeError(w, 405, fmt.Errorf("method %s not allowed", r.Method))
+            return
+    }
+
+    sl.Suppressed = api.silenced.silence(id)
+    w.Write(encodeResp(&Resp{Status:"success", Data:sl}))
+}
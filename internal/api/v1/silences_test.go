@@ -0,0 +1,110 @@
+package v1
+
+import (
+    "time"
+    "strings"
+    "testing"
+    "net/http/httptest"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+func TestMatchSilence(t *testing.T) {
+    alert := map[string]interface{}{
+        "labels": map[string]interface{}{ "alertname": "HostDown", "severity": "critical", "instance": "web-01:9100" },
+    }
+    tests := []struct {
+        name     string
+        matchers []config.Matcher
+        want     bool
+    }{
+        {"equal", []config.Matcher{{ Name: "alertname", Value: "HostDown", IsEqual: true }}, true},
+        {"equal mismatch", []config.Matcher{{ Name: "alertname", Value: "hostdown", IsEqual: true }}, false},
+        {"not equal", []config.Matcher{{ Name: "severity", Value: "warning" }}, true},
+        {"regex", []config.Matcher{{ Name: "instance", Value: "web-.*", IsRegex: true, IsEqual: true }}, true},
+        {"regex is anchored", []config.Matcher{{ Name: "instance", Value: "web", IsRegex: true, IsEqual: true }}, false},
+        {"regex alternatives are anchored", []config.Matcher{{ Name: "severity", Value: "crit|critical", IsRegex: true, IsEqual: true }}, true},
+        {"negative regex", []config.Matcher{{ Name: "severity", Value: "warning|info", IsRegex: true }}, true},
+        {"invalid regex", []config.Matcher{{ Name: "severity", Value: "(", IsRegex: true, IsEqual: true }}, false},
+        {"missing label equals empty", []config.Matcher{{ Name: "team", Value: "", IsEqual: true }}, true},
+        {"missing label", []config.Matcher{{ Name: "team", Value: "ops", IsEqual: true }}, false},
+        {"all must match", []config.Matcher{
+            { Name: "alertname", Value: "HostDown", IsEqual: true },
+            { Name: "severity", Value: "warning", IsEqual: true },
+        }, false},
+        {"no matchers", nil, true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := matchSilence(config.Silence{ Matchers: tt.matchers }, alert); got != tt.want {
+                t.Errorf("matchSilence() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+// silenceDb serves the silences and counts the loads of the active silences
+type silenceDb struct {
+    fakeDb
+    silences     []config.Silence
+    loads        int
+}
+
+func (f *silenceDb) LoadSilences(filter config.SilenceFilter) ([]config.Silence, error) {
+    if filter.Active {
+        f.loads++
+        var active []config.Silence
+        for _, sl := range f.silences {
+            if sl.EndsAt > time.Now().Unix() {
+                active = append(active, sl)
+            }
+        }
+        return active, nil
+    }
+    return f.silences, nil
+}
+
+func (f *silenceDb) SaveSilence(sl config.Silence) (int64, error) {
+    sl.Id = int64(len(f.silences) + 1)
+    f.silences = append(f.silences, sl)
+    return sl.Id, nil
+}
+
+func TestSilenceCache(t *testing.T) {
+    client := &silenceDb{}
+    api := &Api{ Client: client, silences: newSilenceCache() }
+    alert := map[string]interface{}{ "labels": map[string]interface{}{ "alertname": "HostDown" } }
+
+    for i := 0; i < 3; i++ {
+        if _, silenced, err := api.silencedBy(alert); err != nil || silenced {
+            t.Fatalf("silencedBy() = %v, %v without silences", silenced, err)
+        }
+    }
+    if client.loads != 1 {
+        t.Errorf("loaded the silences %d times, want 1", client.loads)
+    }
+
+    // Creating a silence through the API invalidates the cache
+    body := `{"matchers":[{"name":"alertname","value":"HostDown","isEqual":true}],"created_by":"ops","comment":"maintenance","duration":"1h"}`
+    w := httptest.NewRecorder()
+    api.ApiSilences(w, httptest.NewRequest("POST", "/api/v1/silences", strings.NewReader(body)))
+    if w.Code != 200 {
+        t.Fatalf("POST /api/v1/silences: %d %s", w.Code, w.Body)
+    }
+    if sl, silenced, err := api.silencedBy(alert); err != nil || !silenced || sl.Id != 1 {
+        t.Errorf("silencedBy() = %v, %v, %v after creating a silence", sl, silenced, err)
+    }
+    if client.loads != 2 {
+        t.Errorf("loaded the silences %d times, want 2", client.loads)
+    }
+
+    // The cache expires when a silence ends
+    client.silences[0].EndsAt = time.Now().Unix() + 1
+    api.silences.invalidate()
+    if _, silenced, _ := api.silencedBy(alert); !silenced {
+        t.Fatal("silencedBy() did not match the silence before it ends")
+    }
+    time.Sleep(1100 * time.Millisecond)
+    if _, silenced, _ := api.silencedBy(alert); silenced {
+        t.Error("silencedBy() matched an ended silence")
+    }
+}
@@ -0,0 +1,170 @@
+package v1
+
+import (
+    "fmt"
+    "log"
+    "sync"
+    "time"
+    "github.com/ltkh/jiramanager/internal/config"
+    "github.com/ltkh/jiramanager/internal/template"
+)
+
+type storm struct {
+    seen         map[string]time.Time
+    active       bool
+    alerts       []pendingAlert
+}
+
+// stormDetector counts the new alert groups of every receiver within the storm window
+type stormDetector struct {
+    sync.Mutex
+    storms       map[string]*storm
+}
+
+func newStormDetector() *stormDetector {
+    return &stormDetector{ storms: make(map[string]*storm) }
+}
+
+// stormGroup returns the group id of the umbrella issue of the receiver
+func stormGroup(receiver string) string {
+    return getHash("storm:" + receiver)
+}
+
+func (d *stormDetector) get(receiver string) *storm {
+    s, ok := d.storms[receiver]
+    if !ok {
+        s = &storm{ seen: make(map[string]time.Time) }
+        d.storms[receiver] = s
+    }
+    return s
+}
+
+func (s *storm) prune(window time.Duration, now time.Time) {
+    for group_id, seen := range s.seen {
+        if now.Sub(seen) > window {
+            delete(s.seen, group_id)
+        }
+    }
+}
+
+// observe records a new alert group of the receiver and returns whether the receiver is in a storm
+func (d *stormDetector) observe(receiver *config.Receiver, group_id string, now time.Time) bool {
+    if receiver.Storm == nil {
+        return false
+    }
+
+    d.Lock()
+    defer d.Unlock()
+
+    s := d.get(receiver.Name)
+    s.prune(receiver.Storm.Window, now)
+    s.seen[group_id] = now
+
+    if !s.active && len(s.seen) >= receiver.Storm.Threshold {
+        s.active = true
+        log.Printf("[warn] alert storm detected for receiver %s: %d new alerts within %s", receiver.Name, len(s.seen), receiver.Storm.Window)
+    }
+    return s.active
+}
+
+// hold keeps an alert of the storm until it is reported in the umbrella issue
+// and returns false when the alert was dropped because the buffer is full
+func (d *stormDetector) hold(receiver, group_id string, alert map[string]interface{}) bool {
+    d.Lock()
+    defer d.Unlock()
+    s := d.get(receiver)
+    var dropped bool
+    s.alerts, _, dropped = hold(s.alerts, pendingAlert{ groupId: group_id, alert: alert })
+    return !dropped
+}
+
+// take returns and removes the storm alerts of the receiver not reported yet
+func (d *stormDetector) take(receiver string) []pendingAlert {
+    d.Lock()
+    defer d.Unlock()
+    s := d.get(receiver)
+    alerts := s.alerts
+    s.alerts = nil
+    return alerts
+}
+
+// restore puts the alerts back when the umbrella issue could not be created or commented
+// and returns the number of alerts dropped at maxPending
+func (d *stormDetector) restore(receiver string, alerts []pendingAlert) int {
+    d.Lock()
+    defer d.Unlock()
+    s := d.get(receiver)
+    var dropped int
+    s.alerts, dropped = restore(s.alerts, alerts)
+    return dropped
+}
+
+// update ends the storm of the receiver once the new alert groups fall below the threshold
+func (d *stormDetector) update(receiver *config.Receiver, now time.Time) bool {
+    d.Lock()
+    defer d.Unlock()
+
+    s, ok := d.storms[receiver.Name]
+    if !ok || !s.active {
+        return false
+    }
+    if receiver.Storm != nil {
+        s.prune(receiver.Storm.Window, now)
+        if len(s.seen) >= receiver.Storm.Threshold {
+            return false
+        }
+    }
+    s.active = false
+    return true
+}
+
+// isActive reports whether the receiver is in a storm
+func (d *stormDetector) isActive(receiver string) bool {
+    d.Lock()
+    defer d.Unlock()
+    s, ok := d.storms[receiver]
+    return ok && s.active
+}
+
+// flushStorm reports the storm alerts of the receiver in the umbrella issue, the alert groups
+// are stored with the umbrella issue so they are deduplicated like their own issues
+func (api *Api) flushStorm(cfg *config.Config, templates map[string]*template.Template, receiver *config.Receiver) {
+    if alerts := api.storms.take(receiver.Name); len(alerts) > 0 {
+        task, ok, err := api.reportAlerts(cfg, templates, receiver, stormGroup(receiver.Name),
+            fmt.Sprintf("Alert storm: %d alerts for receiver %s", len(alerts), receiver.Name),
+            "An alert storm was detected, the following alerts are reported in this issue instead of their own issues:",
+            "More alerts of the storm:",
+            alerts)
+
+        if err != nil {
+            // The alerts are reported with the next flush
+            log.Printf("[warn] report %d storm alerts of receiver %s: %v, retrying", len(alerts), receiver.Name, err)
+            dropped := api.storms.restore(receiver.Name, alerts)
+            api.limits.countRestoreDropped(receiver.Name, "held in the storm", dropped)
+        }
+
+        if ok {
+            for _, pa := range alerts {
+                tk := config.Issue{
+                    GroupId:    pa.groupId,
+                    StatusId:   task.StatusId,
+                    StatusName: task.StatusName,
+                    Receiver:   receiver.Name,
+                    IssueId:    task.IssueId,
+                    IssueKey:   task.IssueKey,
+                    IssueSelf:  task.IssueSelf,
+                    Labels:     alertLabels(pa.alert),
+                }
+                if err := api.Client.SaveIssue(tk); err != nil {
+                    log.Printf("[error] save issue of storm alert %s in %s: %v", pa.groupId, task.IssueKey, err)
+                    continue
+                }
+                api.saveEvent(config.EventStorm, pa.groupId, task.IssueKey, receiver.Name, pa.alert)
+            }
+        }
+    }
+
+    if api.storms.update(receiver, time.Now()) {
+        log.Printf("[info] alert storm ended for receiver %s", receiver.Name)
+    }
+}
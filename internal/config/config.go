@@ -2,17 +2,135 @@ package config
 
 import (
     "fmt"
+    "log"
+    "time"
+    "strings"
+    "strconv"
     "net/url"
     "io/ioutil"
+    "encoding/json"
     "gopkg.in/yaml.v2"
     "github.com/andygrunwald/go-jira"
+    "golang.org/x/crypto/bcrypt"
 )
 
 type Config struct {
+    Include          []string                `yaml:"include"`
     Defaults         *Defaults               `yaml:"defaults"`
     DB               *DB                     `yaml:"db"`
     Receivers        []*Receiver             `yaml:"receivers"`
     Template         string                  `yaml:"template"`
+    Templates        []string                `yaml:"templates"`
+    TemplateOptions  *TemplateOptions        `yaml:"template_options"`
+    SampleAlert      map[string]interface{}  `yaml:"sample_alert"`
+    Web              *Web                    `yaml:"web"`
+    Readiness        *Readiness              `yaml:"readiness"`
+    ProjectLimits    map[string]*RateLimit   `yaml:"project_limits"`
+    MuteTimeIntervals []*MuteTimeInterval    `yaml:"mute_time_intervals"`
+    AlertSources     []*AlertSource          `yaml:"alert_sources"`
+    sources          map[*Receiver]string
+}
+
+type TemplateOptions struct {
+    Timeout          time.Duration           `yaml:"timeout"`
+    DNSCacheTTL      time.Duration           `yaml:"dns_cache_ttl"`
+    DisableNetwork   bool                    `yaml:"disable_network"`
+    MaxOutputSize    int                     `yaml:"max_output_size"`
+}
+
+type Readiness struct {
+    QueueThreshold   int                     `yaml:"queue_threshold"`
+    UpdateMaxAge     time.Duration           `yaml:"update_max_age"`
+    JiraCacheTTL     time.Duration           `yaml:"jira_cache_ttl"`
+    JiraTimeout      time.Duration           `yaml:"jira_timeout"`
+}
+
+// Actions for alerts arriving within a mute time interval
+const (
+    MuteDefer         = "defer"
+    MuteDrop          = "drop"
+)
+
+// Overflow behaviours of the rate limits
+const (
+    OverflowDrop      = "drop"
+    OverflowQueue     = "queue"
+    OverflowAggregate = "aggregate"
+)
+
+// RateLimit limits the issues created by a receiver or in a project, zero values are not limited
+type RateLimit struct {
+    PerMinute        int                     `yaml:"per_minute"`
+    PerHour          int                     `yaml:"per_hour"`
+    MaxOpen          int                     `yaml:"max_open"`
+    Overflow         string                  `yaml:"overflow"`
+}
+
+// Storm switches a receiver to one umbrella issue when Threshold new alert groups arrive within Window
+type Storm struct {
+    Threshold        int                     `yaml:"threshold"`
+    Window           time.Duration           `yaml:"window"`
+}
+
+// Flapping holds new alerts until they stay firing for MinFiringDuration, alerts changing between
+// firing and resolved Threshold times within Window get one issue with the Label
+type Flapping struct {
+    MinFiringDuration time.Duration          `yaml:"min_firing_duration"`
+    Threshold        int                     `yaml:"threshold"`
+    Window           time.Duration           `yaml:"window"`
+    Label            string                  `yaml:"label"`
+}
+
+// ParentMode creates the issues of alerts with the same link key as sub-tasks of one parent issue
+type ParentMode struct {
+    IssueType        jira.IssueType          `yaml:"issue_type"`
+    SubtaskType      jira.IssueType          `yaml:"subtask_type"`
+    Summary          string                  `yaml:"summary"`
+    Description      string                  `yaml:"description"`
+}
+
+// Attachments uploaded to the created issues
+type Attachments struct {
+    AlertJson        bool                    `yaml:"alert_json"`
+    Urls             []string                `yaml:"urls"`
+    MaxSize          int64                   `yaml:"max_size"`
+    ContentTypes     []string                `yaml:"content_types"`
+    Timeout          time.Duration           `yaml:"timeout"`
+    AllowedUrls      []string                `yaml:"allowed_urls"`
+}
+
+// AlertmanagerSilence silences the alerts of an issue in Alertmanager while it is in progress
+type AlertmanagerSilence struct {
+    Url              string                  `yaml:"url"`
+    Status           []string                `yaml:"status"`
+    Duration         time.Duration           `yaml:"duration"`
+    CreatedBy        string                  `yaml:"created_by"`
+    Timeout          time.Duration           `yaml:"timeout"`
+    User             string                  `yaml:"user"`
+    Password         string                  `yaml:"password"`
+    BearerToken      string                  `yaml:"bearer_token"`
+}
+
+// Escalation is a step applied once to an issue left in one of the statuses for longer than After
+type Escalation struct {
+    After            time.Duration           `yaml:"after"`
+    Status           []string                `yaml:"status"`
+    Priority         jira.Priority           `yaml:"priority"`
+    Assignee         map[string]string       `yaml:"assignee"`
+    Comment          string                  `yaml:"comment"`
+}
+
+type Web struct {
+    TLS              *WebTLS                 `yaml:"tls_server_config"`
+    BasicAuthUsers   map[string]string       `yaml:"basic_auth_users"`
+    BearerTokens     []string                `yaml:"bearer_tokens"`
+    ProtectedPaths   []string                `yaml:"protected_paths"`
+}
+
+type WebTLS struct {
+    CertFile         string                  `yaml:"cert_file"`
+    KeyFile          string                  `yaml:"key_file"`
+    ClientCAFile     string                  `yaml:"client_ca_file"`
 }
 
 type Defaults struct {
@@ -24,8 +142,19 @@ type Defaults struct {
     Priority         jira.Priority           `yaml:"priority"`
     Summary          string                  `yaml:"summary"`
     Description      string                  `yaml:"description"`
+    DescriptionFormat string                 `yaml:"description_format"`
     Components       []jira.Component        `yaml:"components"`
     Fields           map[string]interface{}  `yaml:"fields"`
+    Labels           []string                `yaml:"labels"`
+    SendFields       bool                    `yaml:"send_fields"`
+    TemplateStrict   bool                    `yaml:"template_strict"`
+    FallbackSummary  string                  `yaml:"fallback_summary"`
+    RateLimit        *RateLimit              `yaml:"rate_limit"`
+    Storm            *Storm                  `yaml:"storm"`
+    Flapping         *Flapping               `yaml:"flapping"`
+    Attachments      *Attachments            `yaml:"attachments"`
+    Escalation       []*Escalation           `yaml:"escalation"`
+    AlertmanagerSilence *AlertmanagerSilence `yaml:"alertmanager_silence"`
     ResolveState     []string                `yaml:"resolve_state"`
 }
 
@@ -33,6 +162,7 @@ type DB struct {
     Client           string                  `yaml:"client"`
     ConnString       string                  `yaml:"conn_string"`
     CreationLimit    int                     `yaml:"creation_limit"`         
+    EventRetention   time.Duration           `yaml:"event_retention"`
 }
 
 type Receiver struct {
@@ -43,66 +173,419 @@ type Receiver struct {
     Priority         jira.Priority           `yaml:"priority"`
     Summary          string                  `yaml:"summary"`
     Description      string                  `yaml:"description"`
+    DescriptionFormat string                 `yaml:"description_format"`
     Components       []jira.Component        `yaml:"components"`
     Fields           map[string]interface{}  `yaml:"fields"`
+    Labels           []string                `yaml:"labels"`
+    SendFields       *bool                   `yaml:"send_fields"`
+    Template         string                  `yaml:"template"`
+    TemplateStrict   *bool                   `yaml:"template_strict"`
+    FallbackSummary  string                  `yaml:"fallback_summary"`
+    SampleAlert      map[string]interface{}  `yaml:"sample_alert"`
+    DryRun           bool                    `yaml:"dry_run"`
+    RateLimit        *RateLimit              `yaml:"rate_limit"`
+    Storm            *Storm                  `yaml:"storm"`
+    Flapping         *Flapping               `yaml:"flapping"`
+    LinkBy           []string                `yaml:"link_by"`
+    LinkType         string                  `yaml:"link_type"`
+    ParentMode       *ParentMode             `yaml:"parent_mode"`
+    Attachments      *Attachments            `yaml:"attachments"`
+    Escalation       []*Escalation           `yaml:"escalation"`
+    AlertmanagerSilence *AlertmanagerSilence `yaml:"alertmanager_silence"`
+    MuteTimeIntervals []string               `yaml:"mute_time_intervals"`
+    MuteAction       string                  `yaml:"mute_action"`
+}
+
+// Sends reports whether the priority, labels and fields are set on the created issues
+func (rc *Receiver) Sends() bool {
+    return rc.SendFields != nil && *rc.SendFields
 }
 
 type Issue struct {
+    GroupId          string                  `json:"group_id"`
+    StatusId         string                  `json:"status_id"`
+    StatusName       string                  `json:"status_name"`
+    IssueId          string                  `json:"issue_id"`
+    IssueKey         string                  `json:"issue_key"`
+    IssueSelf        string                  `json:"issue_self"`
+    Created          int64                   `json:"created"`
+    Updated          int64                   `json:"updated"`
+    Template         string                  `json:"template"`
+    Receiver         string                  `json:"receiver"`
+    LinkKey          string                  `json:"link_key"`
+    Escalation       int                     `json:"escalation"`
+    Labels           map[string]string       `json:"labels,omitempty"`
+    SilenceId        string                  `json:"silence_id,omitempty"`
+    SilenceEnds      int64                   `json:"silence_ends,omitempty"`
+}
+
+const (
+    EventCreated       = "created"
+    EventDeduplicated  = "deduplicated"
+    EventStatusChanged = "status-changed"
+    EventCommented     = "commented"
+    EventReopened      = "reopened"
+    EventResolved      = "resolved"
+    EventPurged        = "purged"
+    EventThrottled     = "throttled"
+    EventStorm         = "storm"
+    EventLinked        = "linked"
+    EventEscalated     = "escalated"
+    EventMuted         = "muted"
+    EventSilenced      = "silenced"
+    EventAmSilenced    = "am-silenced"
+    EventAmExpired     = "am-silence-expired"
+    EventFlapping      = "flapping"
+)
+
+type Event struct {
+    Id               int64                   `json:"id"`
+    GroupId          string                  `json:"group_id"`
+    IssueKey         string                  `json:"issue_key"`
+    Fingerprint      string                  `json:"fingerprint"`
+    Event            string                  `json:"event"`
+    Details          string                  `json:"details"`
+    Created          int64                   `json:"created"`
+}
+
+type EventFilter struct {
     GroupId          string
-    StatusId         string
-    StatusName       string
-    IssueId          string
     IssueKey         string
-    IssueSelf        string
-    Created          int64
-    Updated          int64
-    Template         string
+    From             int64
+    To               int64
+    Limit            int
+    Offset           int
+}
+
+// DryRun is the issue a receiver in dry run mode would have created
+type DryRun struct {
+    Id               int64                   `json:"id"`
+    GroupId          string                  `json:"group_id"`
+    Receiver         string                  `json:"receiver"`
+    Fingerprint      string                  `json:"fingerprint"`
+    Payload          json.RawMessage         `json:"payload"`
+    Created          int64                   `json:"created"`
+}
+
+type DryRunFilter struct {
+    GroupId          string
+    Receiver         string
+    From             int64
+    To               int64
+}
+
+// Matcher matches a label by value or regular expression, negated when IsEqual is false
+type Matcher struct {
+    Name             string                  `json:"name"`
+    Value            string                  `json:"value"`
+    IsRegex          bool                    `json:"isRegex"`
+    IsEqual          bool                    `json:"isEqual"`
+}
+
+func (m *Matcher) UnmarshalJSON(data []byte) error {
+    type plain Matcher
+    pm := plain{ IsEqual: true }
+    if err := json.Unmarshal(data, &pm); err != nil {
+        return err
+    }
+    *m = Matcher(pm)
+    return nil
+}
+
+// Silence suppresses the creation of issues for the alerts matching all of its matchers until it ends
+type Silence struct {
+    Id               int64                   `json:"id"`
+    Matchers         []Matcher               `json:"matchers"`
+    CreatedBy        string                  `json:"created_by"`
+    Comment          string                  `json:"comment"`
+    EndsAt           int64                   `json:"ends_at"`
+    Created          int64                   `json:"created"`
+    // Alerts suppressed since the start of the process, not stored in the database
+    Suppressed       int64                   `json:"suppressed"`
 }
 
+type SilenceFilter struct {
+    Id               int64
+    Active           bool
+}
+
+// GetReceiver returns the receiver with the given name or nil
+func (cfg *Config) GetReceiver(name string) *Receiver {
+    for _, rc := range cfg.Receivers {
+        if rc.Name == name {
+            return rc
+        }
+    }
+    return nil
+}
+
+// TemplateFiles returns the glob patterns of the global template files
+func (cfg *Config) TemplateFiles() []string {
+    files := append([]string{}, cfg.Templates...)
+    if cfg.Template != "" {
+        files = append(files, cfg.Template)
+    }
+    return files
+}
+
+// ReceiverTemplateFiles returns the glob patterns of the template files used by the receiver,
+// its own template is loaded last and overrides the global definitions
+func (cfg *Config) ReceiverTemplateFiles(rc *Receiver) []string {
+    files := cfg.TemplateFiles()
+    if rc.Template != "" {
+        files = append(files, rc.Template)
+    }
+    return files
+}
+
+// Errors is the list of problems found in the configuration
+type Errors []error
+
+func (errs Errors) Error() string {
+    msgs := make([]string, len(errs))
+    for i, err := range errs {
+        msgs[i] = err.Error()
+    }
+    return strings.Join(msgs, "; ")
+}
+
+func checkWeb(web *Web) Errors {
+    var errs Errors
+    if web.TLS != nil {
+        if web.TLS.CertFile == "" || web.TLS.KeyFile == "" {
+            errs = append(errs, fmt.Errorf("missing cert_file or key_file in web.tls_server_config"))
+        }
+    }
+    for user, hash := range web.BasicAuthUsers {
+        if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+            errs = append(errs, fmt.Errorf("invalid bcrypt hash for web user %q: %s", user, err))
+        }
+    }
+    for _, path := range web.ProtectedPaths {
+        if !strings.HasPrefix(path, "/") {
+            errs = append(errs, fmt.Errorf("invalid web protected path %q", path))
+        }
+    }
+    return errs
+}
+
+func checkDB(db *DB) Errors {
+    var errs Errors
+    if db == nil {
+        return Errors{fmt.Errorf("missing db section")}
+    }
+    switch db.Client {
+        case "mysql", "sqlite3":
+        case "":
+            errs = append(errs, fmt.Errorf("missing client in db section"))
+        default:
+            errs = append(errs, fmt.Errorf("invalid db client %q, must be mysql or sqlite3", db.Client))
+    }
+    if db.ConnString == "" {
+        errs = append(errs, fmt.Errorf("missing conn_string in db section"))
+    }
+    if db.CreationLimit < 0 {
+        errs = append(errs, fmt.Errorf("invalid creation_limit %d in db section, must not be negative", db.CreationLimit))
+    }
+    if db.EventRetention < 0 {
+        errs = append(errs, fmt.Errorf("invalid event_retention %s in db section, must not be negative", db.EventRetention))
+    }
+    if db.EventRetention == 0 {
+        db.EventRetention = 30 * 24 * time.Hour
+    }
+    return errs
+}
+
+func checkRateLimit(rl *RateLimit, where string) Errors {
+    var errs Errors
+    if rl.PerMinute < 0 || rl.PerHour < 0 || rl.MaxOpen < 0 {
+        errs = append(errs, fmt.Errorf("invalid rate_limit in %s, limits must not be negative", where))
+    }
+    switch rl.Overflow {
+        case "", OverflowDrop, OverflowQueue, OverflowAggregate:
+        default:
+            errs = append(errs, fmt.Errorf("invalid rate_limit overflow %q in %s, must be drop, queue or aggregate", rl.Overflow, where))
+    }
+    return errs
+}
+
+func checkDefaults(defaults *Defaults) Errors {
+    var errs Errors
+    if defaults.User == "" || defaults.Password == "" {
+        errs = append(errs, fmt.Errorf("missing user or password in defaults section"))
+    }
+    if len(defaults.ResolveState) == 0 {
+        errs = append(errs, fmt.Errorf("missing resolve_state in defaults section"))
+    }
+    for _, state := range defaults.ResolveState {
+        if _, err := strconv.ParseUint(state, 10, 64); err != nil {
+            errs = append(errs, fmt.Errorf("invalid resolve_state %q, must be a Jira status id", state))
+        }
+    }
+    return errs
+}
+
+// New reads the configuration file and returns all problems found as Errors
 func New(filename string) (*Config, error) {
+    cfg, errs := Load(filename)
+    if len(errs) > 0 {
+        return cfg, errs
+    }
+    return cfg, nil
+}
+
+// Load reads the configuration file and the included fragments, populates receivers from the defaults and validates it
+func Load(filename string) (*Config, Errors) {
     cfg := &Config{}
 
     content, err := ioutil.ReadFile(filename)
     if err != nil {
-       return cfg, err
+       return cfg, Errors{err}
     }
 
     if err := yaml.UnmarshalStrict(content, cfg); err != nil {
-        return cfg, err
+        return cfg, Errors{err}
+    }
+    cfg.sources = receiverSources(filename, content, cfg.Receivers)
+
+    if errs := cfg.loadIncludes(); len(errs) > 0 {
+        return cfg, errs
     }
 
+    return cfg, cfg.validate()
+}
+
+func (cfg *Config) validate() Errors {
+    var errs Errors
+
+    if cfg.Defaults == nil {
+        errs = append(errs, fmt.Errorf("missing defaults section"))
+        cfg.Defaults = &Defaults{}
+    } else {
+        errs = append(errs, checkDefaults(cfg.Defaults)...)
+    }
+
+    errs = append(errs, checkDB(cfg.DB)...)
+
+    // creation_limit is superseded by rate_limit, it becomes the max_open of the default limits
+    if cfg.DB != nil && cfg.DB.CreationLimit > 0 {
+        log.Printf("[warn] creation_limit in db section is deprecated, applied as max_open of the defaults rate_limit")
+        if cfg.Defaults.RateLimit == nil {
+            cfg.Defaults.RateLimit = &RateLimit{}
+        }
+        if cfg.Defaults.RateLimit.MaxOpen == 0 {
+            cfg.Defaults.RateLimit.MaxOpen = cfg.DB.CreationLimit
+        }
+    }
+
+    if cfg.TemplateOptions == nil {
+        cfg.TemplateOptions = &TemplateOptions{}
+    }
+    if cfg.TemplateOptions.Timeout == 0 {
+        cfg.TemplateOptions.Timeout = 10 * time.Second
+    }
+    if cfg.TemplateOptions.DNSCacheTTL == 0 {
+        cfg.TemplateOptions.DNSCacheTTL = time.Minute
+    }
+    if cfg.TemplateOptions.MaxOutputSize == 0 {
+        cfg.TemplateOptions.MaxOutputSize = 1 << 20
+    }
+    if cfg.TemplateOptions.MaxOutputSize < 0 {
+        errs = append(errs, fmt.Errorf("template_options: max_output_size must not be negative"))
+    }
+
+    if cfg.Readiness == nil {
+        cfg.Readiness = &Readiness{}
+    }
+    if cfg.Readiness.QueueThreshold == 0 {
+        cfg.Readiness.QueueThreshold = 800
+    }
+    if cfg.Readiness.UpdateMaxAge == 0 {
+        cfg.Readiness.UpdateMaxAge = 30 * time.Minute
+    }
+    if cfg.Readiness.JiraCacheTTL == 0 {
+        cfg.Readiness.JiraCacheTTL = 5 * time.Minute
+    }
+    if cfg.Readiness.JiraTimeout == 0 {
+        cfg.Readiness.JiraTimeout = 10 * time.Second
+    }
+
+    if cfg.Web != nil {
+        errs = append(errs, checkWeb(cfg.Web)...)
+    }
+
+    for project, rl := range cfg.ProjectLimits {
+        if rl == nil {
+            errs = append(errs, fmt.Errorf("empty project_limits entry for project %q", project))
+            continue
+        }
+        errs = append(errs, checkRateLimit(rl, fmt.Sprintf("project_limits of project %q", project))...)
+        if rl.Overflow != "" {
+            errs = append(errs, fmt.Errorf("overflow is not supported in project_limits of project %q, it is set per receiver", project))
+        }
+    }
+
+    intervals := make(map[string]bool)
+    for _, mti := range cfg.MuteTimeIntervals {
+        switch {
+            case mti == nil || mti.Name == "":
+                errs = append(errs, fmt.Errorf("missing name for mute time interval"))
+                continue
+            case intervals[mti.Name]:
+                errs = append(errs, fmt.Errorf("duplicate mute time interval name %q", mti.Name))
+        }
+        intervals[mti.Name] = true
+    }
+
+    if len(cfg.Receivers) == 0 {
+        errs = append(errs, fmt.Errorf("no receivers defined"))
+    }
+
+    names := make(map[string]*Receiver)
     for _, rc := range cfg.Receivers {
+        if rc == nil {
+            errs = append(errs, fmt.Errorf("empty receiver definition"))
+            continue
+        }
         if rc.Name == "" {
-            return cfg, fmt.Errorf("missing name for receiver %+v", rc)
+            errs = append(errs, fmt.Errorf("missing name for receiver at %s", cfg.source(rc)))
+            continue
+        }
+        if prev, ok := names[rc.Name]; ok {
+            errs = append(errs, fmt.Errorf("duplicate receiver name %q at %s, already defined at %s", rc.Name, cfg.source(rc), cfg.source(prev)))
+        } else {
+            names[rc.Name] = rc
         }
 
         // Check API access fields
         if rc.ApiUrl == "" {
             if cfg.Defaults.ApiUrl == "" {
-                return cfg, fmt.Errorf("missing api_url in receiver %q", rc.Name)
+                errs = append(errs, fmt.Errorf("missing api_url in receiver %q", rc.Name))
             }
             rc.ApiUrl = cfg.Defaults.ApiUrl
         }
-        if _, err := url.Parse(rc.ApiUrl); err != nil {
-            return cfg, fmt.Errorf("invalid api_url %q in receiver %q: %s", rc.ApiUrl, rc.Name, err)
+        if u, err := url.Parse(rc.ApiUrl); err != nil {
+            errs = append(errs, fmt.Errorf("invalid api_url %q in receiver %q: %s", rc.ApiUrl, rc.Name, err))
+        } else if rc.ApiUrl != "" && (u.Scheme == "" || u.Host == "") {
+            errs = append(errs, fmt.Errorf("invalid api_url %q in receiver %q: missing scheme or host", rc.ApiUrl, rc.Name))
         }
 
         // Check required issue fields
         if rc.Project.ID == "" && rc.Project.Key == "" && rc.Project.Name == "" {
             if cfg.Defaults.Project.ID == "" && cfg.Defaults.Project.Key == "" && cfg.Defaults.Project.Name == "" {
-                return cfg, fmt.Errorf("missing project in receiver %q", rc.Name)
+                errs = append(errs, fmt.Errorf("missing project in receiver %q", rc.Name))
             }
             rc.Project = cfg.Defaults.Project
         }
         if rc.IssueType.ID == "" && rc.IssueType.Name == "" {
             if cfg.Defaults.IssueType.ID == "" && cfg.Defaults.IssueType.Name == "" {
-                return cfg, fmt.Errorf("missing issue_type in receiver %q", rc.Name)
+                errs = append(errs, fmt.Errorf("missing issue_type in receiver %q", rc.Name))
             }
             rc.IssueType = cfg.Defaults.IssueType
         }
         if rc.Summary == "" {
             if cfg.Defaults.Summary == "" {
-                return cfg, fmt.Errorf("missing summary in receiver %q", rc.Name)
+                errs = append(errs, fmt.Errorf("missing summary in receiver %q", rc.Name))
             }
             rc.Summary = cfg.Defaults.Summary
         }
@@ -116,7 +599,173 @@ func New(filename string) (*Config, error) {
         if rc.Description == "" && cfg.Defaults.Description != "" {
             rc.Description = cfg.Defaults.Description
         }
+        if rc.TemplateStrict == nil {
+            strict := cfg.Defaults.TemplateStrict
+            rc.TemplateStrict = &strict
+        }
+        if rc.FallbackSummary == "" {
+            rc.FallbackSummary = cfg.Defaults.FallbackSummary
+        }
+        if rc.FallbackSummary == "" {
+            rc.FallbackSummary = DefaultFallbackSummary
+        }
+        if rc.Labels == nil {
+            rc.Labels = cfg.Defaults.Labels
+        }
+        if rc.SendFields == nil {
+            send := cfg.Defaults.SendFields
+            rc.SendFields = &send
+        }
+        if rc.DescriptionFormat == "" {
+            rc.DescriptionFormat = cfg.Defaults.DescriptionFormat
+        }
+        if rc.RateLimit == nil && cfg.Defaults.RateLimit != nil {
+            rl := *cfg.Defaults.RateLimit
+            rc.RateLimit = &rl
+        }
+        if rc.RateLimit != nil {
+            errs = append(errs, checkRateLimit(rc.RateLimit, fmt.Sprintf("receiver %q", rc.Name))...)
+            if rc.RateLimit.Overflow == "" {
+                rc.RateLimit.Overflow = OverflowDrop
+            }
+        }
+        if rc.Storm == nil && cfg.Defaults.Storm != nil {
+            storm := *cfg.Defaults.Storm
+            rc.Storm = &storm
+        }
+        if rc.Storm != nil {
+            if rc.Storm.Threshold <= 0 {
+                errs = append(errs, fmt.Errorf("invalid storm threshold %d in receiver %q, must be greater than 0", rc.Storm.Threshold, rc.Name))
+            }
+            if rc.Storm.Window < 0 {
+                errs = append(errs, fmt.Errorf("invalid storm window %s in receiver %q", rc.Storm.Window, rc.Name))
+            }
+            if rc.Storm.Window == 0 {
+                rc.Storm.Window = time.Minute
+            }
+        }
+        if rc.Flapping == nil && cfg.Defaults.Flapping != nil {
+            flapping := *cfg.Defaults.Flapping
+            rc.Flapping = &flapping
+        }
+        if fl := rc.Flapping; fl != nil {
+            if fl.MinFiringDuration < 0 || fl.Threshold < 0 || fl.Window < 0 {
+                errs = append(errs, fmt.Errorf("invalid flapping in receiver %q, min_firing_duration, threshold and window must not be negative", rc.Name))
+            }
+            if fl.MinFiringDuration == 0 && fl.Threshold == 0 {
+                errs = append(errs, fmt.Errorf("invalid flapping in receiver %q, min_firing_duration or threshold is required", rc.Name))
+            }
+            if fl.Window == 0 {
+                fl.Window = time.Hour
+            }
+            if fl.Label == "" {
+                fl.Label = "flapping"
+            }
+        }
+        if rc.Attachments == nil && cfg.Defaults.Attachments != nil {
+            at := *cfg.Defaults.Attachments
+            rc.Attachments = &at
+        }
+        if rc.AlertmanagerSilence == nil && cfg.Defaults.AlertmanagerSilence != nil {
+            ams := *cfg.Defaults.AlertmanagerSilence
+            rc.AlertmanagerSilence = &ams
+        }
+        if ams := rc.AlertmanagerSilence; ams != nil {
+            if u, err := url.Parse(ams.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+                errs = append(errs, fmt.Errorf("invalid alertmanager_silence url %q in receiver %q", ams.Url, rc.Name))
+            }
+            if len(ams.Status) == 0 {
+                errs = append(errs, fmt.Errorf("missing alertmanager_silence status in receiver %q", rc.Name))
+            }
+            if ams.Duration < 0 || ams.Timeout < 0 {
+                errs = append(errs, fmt.Errorf("invalid alertmanager_silence in receiver %q, duration and timeout must not be negative", rc.Name))
+            }
+            if ams.Duration == 0 {
+                ams.Duration = time.Hour
+            }
+            if ams.Timeout == 0 {
+                ams.Timeout = 10 * time.Second
+            }
+            if ams.CreatedBy == "" {
+                ams.CreatedBy = "jiramanager"
+            }
+            if ams.User != "" && ams.BearerToken != "" {
+                errs = append(errs, fmt.Errorf("user and bearer_token of alertmanager_silence in receiver %q are mutually exclusive", rc.Name))
+            }
+        }
+        if at := rc.Attachments; at != nil {
+            if at.MaxSize < 0 || at.Timeout < 0 {
+                errs = append(errs, fmt.Errorf("invalid attachments in receiver %q, max_size and timeout must not be negative", rc.Name))
+            }
+            if at.MaxSize == 0 {
+                at.MaxSize = 5 << 20
+            }
+            if at.Timeout == 0 {
+                at.Timeout = 10 * time.Second
+            }
+            if len(at.ContentTypes) == 0 {
+                at.ContentTypes = []string{"image/*"}
+            }
+            for _, a := range at.AllowedUrls {
+                if !strings.Contains(a, "://") {
+                    continue
+                }
+                if u, err := url.Parse(a); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+                    errs = append(errs, fmt.Errorf("invalid allowed_urls %q in attachments of receiver %q, must be a host or an http(s) url prefix", a, rc.Name))
+                }
+            }
+        }
+        if rc.Escalation == nil {
+            rc.Escalation = cfg.Defaults.Escalation
+        }
+        for i, step := range rc.Escalation {
+            switch {
+                case step == nil:
+                    errs = append(errs, fmt.Errorf("empty escalation step %d in receiver %q", i + 1, rc.Name))
+                case step.After <= 0:
+                    errs = append(errs, fmt.Errorf("invalid after %s in escalation step %d of receiver %q, must be greater than 0", step.After, i + 1, rc.Name))
+                case step.Priority.ID == "" && step.Priority.Name == "" && len(step.Assignee) == 0 && step.Comment == "":
+                    errs = append(errs, fmt.Errorf("escalation step %d of receiver %q has no priority, assignee or comment", i + 1, rc.Name))
+            }
+        }
+        for _, name := range rc.MuteTimeIntervals {
+            if !intervals[name] {
+                errs = append(errs, fmt.Errorf("unknown mute time interval %q in receiver %q", name, rc.Name))
+            }
+        }
+        switch rc.MuteAction {
+            case "":
+                rc.MuteAction = MuteDefer
+            case MuteDefer, MuteDrop:
+            default:
+                errs = append(errs, fmt.Errorf("invalid mute_action %q in receiver %q, must be defer or drop", rc.MuteAction, rc.Name))
+        }
+        if len(rc.LinkBy) > 0 && rc.LinkType == "" {
+            rc.LinkType = "Relates"
+        }
+        if pm := rc.ParentMode; pm != nil {
+            if len(rc.LinkBy) == 0 {
+                errs = append(errs, fmt.Errorf("missing link_by for parent_mode in receiver %q", rc.Name))
+            }
+            if pm.SubtaskType.ID == "" && pm.SubtaskType.Name == "" {
+                errs = append(errs, fmt.Errorf("missing subtask_type in parent_mode of receiver %q", rc.Name))
+            }
+            if pm.Summary == "" {
+                errs = append(errs, fmt.Errorf("missing summary in parent_mode of receiver %q", rc.Name))
+            }
+            if pm.IssueType.ID == "" && pm.IssueType.Name == "" {
+                pm.IssueType = rc.IssueType
+            }
+        }
+        switch rc.DescriptionFormat {
+            case "", "markdown", "wiki", "adf":
+            default:
+                errs = append(errs, fmt.Errorf("invalid description_format %q in receiver %q", rc.DescriptionFormat, rc.Name))
+        }
         if len(cfg.Defaults.Fields) > 0 {
+            if rc.Fields == nil {
+                rc.Fields = make(map[string]interface{})
+            }
             for key, value := range cfg.Defaults.Fields {
                 if _, ok := rc.Fields[key]; !ok {
                     rc.Fields[key] = value
@@ -124,7 +773,13 @@ func New(filename string) (*Config, error) {
             }
         }
     }
-    
-    return cfg, nil
-}
 
+    // Render all receiver templates with the sample alert
+    if len(names) > 0 {
+        errs = append(errs, cfg.checkTemplates()...)
+    }
+
+    errs = append(errs, cfg.checkSources()...)
+
+    return errs
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+    "strings"
+    "testing"
+    "time"
+    "io/ioutil"
+    "path/filepath"
+)
+
+// loadConfig writes the configuration next to the files and loads it
+func loadConfig(t *testing.T, config string, files map[string]string) (*Config, Errors) {
+    if files == nil {
+        files = map[string]string{}
+    }
+    if _, ok := files["test.tmpl"]; !ok {
+        files["test.tmpl"] = `{{ define "test" }}{{ end }}`
+    }
+    dir := writeFiles(t, files)
+    path := filepath.Join(dir, "config.yml")
+    if err := ioutil.WriteFile(path, []byte(strings.ReplaceAll(config, "$DIR", dir)), 0644); err != nil {
+        t.Fatal(err)
+    }
+    return Load(path)
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+    _, errs := loadConfig(t, `
+db:
+  client: postgres
+defaults:
+  user: jiramanager
+  resolve_state: ["done"]
+receivers:
+  - name: jira
+    description_format: html
+`, nil)
+
+    want := []string{
+        `invalid db client "postgres"`,
+        "missing conn_string in db section",
+        "missing user or password in defaults section",
+        `invalid resolve_state "done"`,
+        `missing api_url in receiver "jira"`,
+        `missing project in receiver "jira"`,
+        `missing issue_type in receiver "jira"`,
+        `missing summary in receiver "jira"`,
+        `invalid description_format "html" in receiver "jira"`,
+    }
+    for _, w := range want {
+        found := false
+        for _, err := range errs {
+            if strings.Contains(err.Error(), w) {
+                found = true
+            }
+        }
+        if !found {
+            t.Errorf("Load() = %v, missing %q", errs, w)
+        }
+    }
+}
+
+func TestLoadDefaults(t *testing.T) {
+    cfg, errs := loadConfig(t, testConfig("$DIR", `
+  - name: jira
+`), nil)
+    if len(errs) > 0 {
+        t.Fatalf("Load() = %v", errs)
+    }
+
+    if cfg.DB.EventRetention != 30 * 24 * time.Hour {
+        t.Errorf("db event_retention %s", cfg.DB.EventRetention)
+    }
+    if to := cfg.TemplateOptions; to == nil || to.Timeout != 10 * time.Second || to.DNSCacheTTL != time.Minute || to.MaxOutputSize != 1 << 20 {
+        t.Errorf("template_options %+v", to)
+    }
+    if r := cfg.Readiness; r == nil || r.QueueThreshold != 800 || r.UpdateMaxAge != 30 * time.Minute || r.JiraCacheTTL != 5 * time.Minute || r.JiraTimeout != 10 * time.Second {
+        t.Errorf("readiness %+v", r)
+    }
+
+    rc := cfg.Receivers[0]
+    if rc.ApiUrl != "https://jira.example.com" || rc.Project.Key != "TEST" || rc.IssueType.Name != "Bug" || rc.Summary != "{{ .labels.alertname }}" {
+        t.Errorf("receiver %+v, want the api_url, project, issue_type and summary of the defaults", rc)
+    }
+    if rc.TemplateStrict == nil || *rc.TemplateStrict || rc.Sends() {
+        t.Errorf("receiver template_strict %v, send_fields %v, want false", rc.TemplateStrict, rc.SendFields)
+    }
+    if rc.FallbackSummary != DefaultFallbackSummary || rc.MuteAction != MuteDefer {
+        t.Errorf("receiver fallback_summary %q, mute_action %q", rc.FallbackSummary, rc.MuteAction)
+    }
+}
+
+func TestLoadDuplicateNames(t *testing.T) {
+    tests := []struct {
+        name     string
+        config   string
+        files    map[string]string
+        err      string
+    }{
+        {"receivers", testConfig("$DIR", `
+  - name: jira
+  - name: jira
+`), nil, `duplicate receiver name "jira"`},
+        {"template namespaces", strings.Replace(testConfig("$DIR", `
+  - name: jira
+`), "template: $DIR/test.tmpl", "templates: [$DIR/test.tmpl, $DIR/other/test.tmpl]", 1), map[string]string{
+            "other/test.tmpl": `{{ define "other" }}{{ end }}`,
+        }, `share the namespace "test"`},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, errs := loadConfig(t, tt.config, tt.files)
+            if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.err) {
+                t.Errorf("Load() = %v, want %q", errs, tt.err)
+            }
+        })
+    }
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "io/ioutil"
+    "path/filepath"
+    "gopkg.in/yaml.v2"
+)
+
+// Fragment is a file included by the main configuration, it may only define receivers
+type Fragment struct {
+    Receivers        []*Receiver             `yaml:"receivers"`
+}
+
+// indent returns the number of leading spaces of the line, or -1 for blank and comment lines
+func indent(line string) int {
+    trimmed := strings.TrimLeft(line, " ")
+    if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+        return -1
+    }
+    return len(line) - len(trimmed)
+}
+
+// receiverSources returns the "file:line" position of every receiver defined in the file content,
+// the items of the top level receivers sequence are matched to the receivers by their index
+func receiverSources(filename string, content []byte, receivers []*Receiver) map[*Receiver]string {
+    sources := make(map[*Receiver]string, len(receivers))
+    for _, rc := range receivers {
+        if rc != nil {
+            sources[rc] = filename
+        }
+    }
+    lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+
+    start := -1
+    for i, line := range lines {
+        if strings.HasPrefix(line, "receivers:") && indent(line) == 0 {
+            start = i + 1
+            break
+        }
+    }
+    if start < 0 {
+        return sources
+    }
+
+    // The line of an item is the line of its name key, or the line of the dash without a name
+    item, seq, key := -1, -1, -1
+    for i := start; i < len(lines) && item < len(receivers); i++ {
+        n := indent(lines[i])
+        if n < 0 {
+            continue
+        }
+        text := lines[i][n:]
+        if seq < 0 {
+            if !strings.HasPrefix(text, "-") {
+                break
+            }
+            seq = n
+        }
+        if n < seq || (n == seq && !strings.HasPrefix(text, "-")) {
+            break
+        }
+
+        if n == seq {
+            item++
+            if item >= len(receivers) {
+                break
+            }
+            if rc := receivers[item]; rc != nil {
+                sources[rc] = fmt.Sprintf("%s:%d", filename, i + 1)
+            }
+            rest := strings.TrimLeft(text[1:], " ")
+            if rest == "" {
+                key = -1
+                continue
+            }
+            key = n + len(text) - len(rest)
+            text, n = rest, key
+        }
+        if key < 0 {
+            key = n
+        }
+        if n == key && strings.HasPrefix(text, "name:") {
+            if rc := receivers[item]; rc != nil {
+                sources[rc] = fmt.Sprintf("%s:%d", filename, i + 1)
+            }
+        }
+    }
+
+    return sources
+}
+
+// includeFiles returns the files matching the include patterns, a pattern without matches is not an error
+func (cfg *Config) includeFiles() ([]string, error) {
+    var files []string
+    for _, pattern := range cfg.Include {
+        matches, err := filepath.Glob(pattern)
+        if err != nil {
+            return nil, fmt.Errorf("invalid include pattern %q: %v", pattern, err)
+        }
+        sort.Strings(matches)
+        files = append(files, matches...)
+    }
+    return files, nil
+}
+
+// loadIncludes appends the receivers of all included fragments to the configuration
+func (cfg *Config) loadIncludes() Errors {
+    files, err := cfg.includeFiles()
+    if err != nil {
+        return Errors{err}
+    }
+
+    var errs Errors
+    for _, file := range files {
+        content, err := ioutil.ReadFile(file)
+        if err != nil {
+            errs = append(errs, err)
+            continue
+        }
+
+        fragment := &Fragment{}
+        if err := yaml.UnmarshalStrict(content, fragment); err != nil {
+            errs = append(errs, fmt.Errorf("%s: %v", file, err))
+            continue
+        }
+
+        for rc, source := range receiverSources(file, content, fragment.Receivers) {
+            cfg.sources[rc] = source
+        }
+        cfg.Receivers = append(cfg.Receivers, fragment.Receivers...)
+    }
+
+    return errs
+}
+
+// source returns the position where the receiver is defined, if known
+func (cfg *Config) source(rc *Receiver) string {
+    if source, ok := cfg.sources[rc]; ok {
+        return source
+    }
+    return "unknown position"
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestReceiverSources(t *testing.T) {
+    content := `
+defaults:
+  issue_type:
+    name: ops
+receivers:
+  # the first receiver
+  - name: other
+    issue_type:
+      name: ops
+    description: |
+      - name: ops
+  -
+    project:
+      key: OPS
+    name: "ops"
+  - {name: flow}
+mute_time_intervals:
+  - name: ops
+`
+    receivers := []*Receiver{ &Receiver{ Name: "other" }, &Receiver{ Name: "ops" }, &Receiver{ Name: "flow" } }
+    sources := receiverSources("config.yml", []byte(content), receivers)
+
+    for i, want := range []string{"config.yml:7", "config.yml:15", "config.yml:16"} {
+        if sources[receivers[i]] != want {
+            t.Errorf("source of receiver %q = %q, want %q", receivers[i].Name, sources[receivers[i]], want)
+        }
+    }
+}
+
+func TestLoadIncludedDuplicateReceiver(t *testing.T) {
+    _, errs := loadConfig(t, testConfig("$DIR", `
+  - name: jira
+include:
+  - $DIR/receivers/*.yml
+`), map[string]string{
+        "receivers/a.yml": `receivers:
+  - name: other
+    issue_type:
+      name: ops
+  - name: ops
+`,
+        "receivers/b.yml": `
+receivers:
+- name: ops
+`,
+    })
+
+    if len(errs) != 1 {
+        t.Fatalf("Load() = %v, want one duplicate receiver error", errs)
+    }
+    got := errs[0].Error()
+    if !strings.HasPrefix(got, `duplicate receiver name "ops" at `) || !strings.Contains(got, "/receivers/b.yml:3, already defined at ") || !strings.HasSuffix(got, "/receivers/a.yml:5") {
+        t.Errorf("Load() = %q, want the positions b.yml:3 and a.yml:5", got)
+    }
+}
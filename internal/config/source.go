@@ -0,0 +1,115 @@
+package config
+
+import (
+    "fmt"
+    "time"
+    "regexp"
+    "net/url"
+)
+
+// Types of the alert sources
+const (
+    SourcePrometheus   = "prometheus"
+    SourceAlertmanager = "alertmanager"
+)
+
+// AlertSource is polled for its active alerts, which are sent to the receiver like webhook alerts
+type AlertSource struct {
+    Name             string                  `yaml:"name"`
+    Type             string                  `yaml:"type"`
+    Url              string                  `yaml:"url"`
+    Receiver         string                  `yaml:"receiver"`
+    Interval         time.Duration           `yaml:"interval"`
+    RepeatInterval   time.Duration           `yaml:"repeat_interval"`
+    Timeout          time.Duration           `yaml:"timeout"`
+    Filters          []string                `yaml:"filters"`
+    User             string                  `yaml:"user"`
+    Password         string                  `yaml:"password"`
+    Matchers         []Matcher               `yaml:"-"`
+}
+
+var matcherRegexp = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*"((?:[^"\\]|\\.)*)"\s*$`)
+
+// ParseMatcher parses a matcher like name="value", name!="value", name=~"regex" or name!~"regex"
+func ParseMatcher(text string) (Matcher, error) {
+    m := matcherRegexp.FindStringSubmatch(text)
+    if m == nil {
+        return Matcher{}, fmt.Errorf("invalid matcher %q", text)
+    }
+
+    value := regexp.MustCompile(`\\(.)`).ReplaceAllString(m[3], "$1")
+    matcher := Matcher{
+        Name:       m[1],
+        Value:      value,
+        IsRegex:    m[2] == "=~" || m[2] == "!~",
+        IsEqual:    m[2] == "=" || m[2] == "=~",
+    }
+    if matcher.IsRegex {
+        if _, err := regexp.Compile("^(?:" + value + ")$"); err != nil {
+            return Matcher{}, fmt.Errorf("invalid matcher %q: %v", text, err)
+        }
+    }
+    return matcher, nil
+}
+
+// GetAlertSource returns the alert source with the given name or nil
+func (cfg *Config) GetAlertSource(name string) *AlertSource {
+    for _, src := range cfg.AlertSources {
+        if src.Name == name {
+            return src
+        }
+    }
+    return nil
+}
+
+func (cfg *Config) checkSources() Errors {
+    var errs Errors
+
+    names := make(map[string]bool)
+    for _, src := range cfg.AlertSources {
+        if src == nil || src.Name == "" {
+            errs = append(errs, fmt.Errorf("missing name for alert source"))
+            continue
+        }
+        if names[src.Name] {
+            errs = append(errs, fmt.Errorf("duplicate alert source name %q", src.Name))
+        }
+        names[src.Name] = true
+
+        switch src.Type {
+            case SourcePrometheus, SourceAlertmanager:
+            default:
+                errs = append(errs, fmt.Errorf("invalid type %q in alert source %q, must be prometheus or alertmanager", src.Type, src.Name))
+        }
+        if u, err := url.Parse(src.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+            errs = append(errs, fmt.Errorf("invalid url %q in alert source %q", src.Url, src.Name))
+        }
+        if cfg.GetReceiver(src.Receiver) == nil {
+            errs = append(errs, fmt.Errorf("unknown receiver %q in alert source %q", src.Receiver, src.Name))
+        }
+        if src.Interval < 0 || src.RepeatInterval < 0 || src.Timeout < 0 {
+            errs = append(errs, fmt.Errorf("invalid alert source %q, interval, repeat_interval and timeout must not be negative", src.Name))
+        }
+        if src.Interval == 0 {
+            src.Interval = time.Minute
+        }
+        if src.RepeatInterval == 0 {
+            src.RepeatInterval = 4 * time.Hour
+        }
+        if src.Timeout == 0 {
+            src.Timeout = 10 * time.Second
+        }
+
+        src.Matchers = nil
+        for _, filter := range src.Filters {
+            matcher, err := ParseMatcher(filter)
+            if err != nil {
+                errs = append(errs, fmt.Errorf("%v in alert source %q", err, src.Name))
+                continue
+            }
+            src.Matchers = append(src.Matchers, matcher)
+        }
+    }
+
+    return errs
+}
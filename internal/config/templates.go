@@ -0,0 +1,212 @@
+package config
+
+import (
+    "fmt"
+    "sort"
+    "time"
+    "github.com/ltkh/jiramanager/internal/template"
+)
+
+// Default summary used when the receiver templates fail to render
+const DefaultFallbackSummary = `{{ .labels.alertname }} {{ toJson .labels }}`
+
+// GetSampleAlert returns the alert the receiver templates are validated against
+func (cfg *Config) GetSampleAlert(rc *Receiver) map[string]interface{} {
+    if rc != nil && rc.SampleAlert != nil {
+        return normalize(rc.SampleAlert).(map[string]interface{})
+    }
+    if cfg.SampleAlert != nil {
+        return normalize(cfg.SampleAlert).(map[string]interface{})
+    }
+    return map[string]interface{}{
+        "status": "firing",
+        "labels": map[string]interface{}{
+            "alertname": "SampleAlert",
+            "instance":  "localhost:9100",
+            "job":       "node",
+            "severity":  "warning",
+        },
+        "annotations": map[string]interface{}{
+            "summary":     "Sample alert",
+            "description": "Sample alert description",
+        },
+        "startsAt":     time.Now().UTC().Format(time.RFC3339),
+        "endsAt":       "0001-01-01T00:00:00Z",
+        "generatorURL": "http://localhost:9090/graph",
+        "fingerprint":  "0000000000000000",
+    }
+}
+
+// normalize converts the maps decoded from YAML to maps with string keys
+func normalize(value interface{}) interface{} {
+    switch v := value.(type) {
+        case map[interface{}]interface{}:
+            result := make(map[string]interface{}, len(v))
+            for key, item := range v {
+                result[fmt.Sprintf("%v", key)] = normalize(item)
+            }
+            return result
+        case map[string]interface{}:
+            result := make(map[string]interface{}, len(v))
+            for key, item := range v {
+                result[key] = normalize(item)
+            }
+            return result
+        case []interface{}:
+            result := make([]interface{}, len(v))
+            for i, item := range v {
+                result[i] = normalize(item)
+            }
+            return result
+    }
+    return value
+}
+
+func (cfg *Config) templateOptions() template.Options {
+    opts := template.Options{}
+    if cfg.TemplateOptions != nil {
+        opts.Timeout = cfg.TemplateOptions.Timeout
+        opts.DNSCacheTTL = cfg.TemplateOptions.DNSCacheTTL
+        opts.DisableNetwork = cfg.TemplateOptions.DisableNetwork
+        opts.MaxOutputSize = cfg.TemplateOptions.MaxOutputSize
+    }
+    return opts
+}
+
+// LoadTemplates loads the global template set and the template set of every receiver
+func (cfg *Config) LoadTemplates() (*template.Template, map[string]*template.Template, error) {
+    var tmpl *template.Template
+    var err error
+
+    opts := cfg.templateOptions()
+
+    if files := cfg.TemplateFiles(); len(files) > 0 {
+        tmpl, err = template.LoadTemplates(files, opts)
+        if err != nil {
+            return nil, nil, err
+        }
+    }
+
+    templates := make(map[string]*template.Template)
+    for _, rc := range cfg.Receivers {
+        var rt *template.Template
+        if rc.Template == "" {
+            if tmpl == nil {
+                return nil, nil, fmt.Errorf("missing template for receiver %q", rc.Name)
+            }
+            rt = tmpl
+        } else {
+            rt, err = template.LoadTemplates(cfg.ReceiverTemplateFiles(rc), opts)
+            if err != nil {
+                return nil, nil, fmt.Errorf("receiver %q: %v", rc.Name, err)
+            }
+        }
+        if rc.TemplateStrict != nil && *rc.TemplateStrict {
+            ropts := opts
+            ropts.Strict = true
+            rt = rt.WithOptions(ropts)
+        }
+        templates[rc.Name] = rt
+    }
+
+    return tmpl, templates, nil
+}
+
+// checkValue executes the templates in all strings of a field value
+func checkValue(tmpl *template.Template, value interface{}, alert map[string]interface{}) error {
+    switch v := normalize(value).(type) {
+        case string:
+            _, err := tmpl.Execute(v, alert)
+            return err
+        case []interface{}:
+            for _, item := range v {
+                if err := checkValue(tmpl, item, alert); err != nil {
+                    return err
+                }
+            }
+        case map[string]interface{}:
+            for _, item := range v {
+                if err := checkValue(tmpl, item, alert); err != nil {
+                    return err
+                }
+            }
+    }
+    return nil
+}
+
+// checkTemplates renders the templates of every receiver with its sample alert
+func (cfg *Config) checkTemplates() Errors {
+    _, templates, err := cfg.LoadTemplates()
+    if err != nil {
+        return Errors{fmt.Errorf("invalid templates: %v", err)}
+    }
+
+    var errs Errors
+    for _, rc := range cfg.Receivers {
+        if rc == nil || rc.Name == "" {
+            continue
+        }
+        tmpl := templates[rc.Name]
+        alert := cfg.GetSampleAlert(rc)
+
+        texts := map[string]string{
+            "summary":          rc.Summary,
+            "description":      rc.Description,
+            "fallback_summary": rc.FallbackSummary,
+        }
+        if rc.Sends() {
+            for i, label := range rc.Labels {
+                texts[fmt.Sprintf("labels[%d]", i)] = label
+            }
+        }
+        if rc.Attachments != nil {
+            for i, u := range rc.Attachments.Urls {
+                texts[fmt.Sprintf("attachments.urls[%d]", i)] = u
+            }
+        }
+        if rc.ParentMode != nil {
+            texts["parent_mode.summary"] = rc.ParentMode.Summary
+            texts["parent_mode.description"] = rc.ParentMode.Description
+        }
+
+        for _, name := range sortedKeys(texts) {
+            undefined, err := tmpl.Undefined(texts[name])
+            if err != nil {
+                errs = append(errs, fmt.Errorf("invalid %s in receiver %q: %v", name, rc.Name, err))
+                continue
+            }
+            if len(undefined) > 0 {
+                errs = append(errs, fmt.Errorf("unknown templates %q referenced by %s in receiver %q", undefined, name, rc.Name))
+                continue
+            }
+
+            t := tmpl
+            if name == "fallback_summary" {
+                t = tmpl.WithOptions(cfg.templateOptions())
+            }
+            if _, err := t.Execute(texts[name], alert); err != nil {
+                errs = append(errs, fmt.Errorf("invalid %s in receiver %q: %v", name, rc.Name, err))
+            }
+        }
+
+        for key, value := range rc.Fields {
+            if !rc.Sends() {
+                break
+            }
+            if err := checkValue(tmpl, value, alert); err != nil {
+                errs = append(errs, fmt.Errorf("invalid field %s in receiver %q: %v", key, rc.Name, err))
+            }
+        }
+    }
+
+    return errs
+}
+
+func sortedKeys(m map[string]string) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
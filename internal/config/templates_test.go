@@ -0,0 +1,90 @@
+package config
+
+import (
+    "os"
+    "fmt"
+    "strings"
+    "testing"
+    "io/ioutil"
+    "path/filepath"
+)
+
+// writeFiles writes the files to a temporary directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+    dir, err := ioutil.TempDir("", "config")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { os.RemoveAll(dir) })
+    for name, content := range files {
+        if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return dir
+}
+
+// testConfig returns a valid configuration using the template of dir, followed by the receivers
+func testConfig(dir, receivers string) string {
+    return fmt.Sprintf(`
+db:
+  client: sqlite3
+  conn_string: %s
+template: %s
+defaults:
+  api_url: https://jira.example.com
+  user: jiramanager
+  password: secret
+  resolve_state: ["5"]
+  project:
+    key: TEST
+  issue_type:
+    name: Bug
+  summary: '{{ .labels.alertname }}'
+receivers:
+%s`, filepath.Join(dir, "db.sqlite"), filepath.Join(dir, "test.tmpl"), receivers)
+}
+
+func TestCheckTemplatesStrict(t *testing.T) {
+    tests := []struct {
+        name     string
+        receiver string
+        err      string
+    }{
+        {"default ignores a missing key", `
+  - name: jira
+    summary: '{{ .labels.missing }}'`, ""},
+        {"strict rejects a missing key", `
+  - name: jira
+    template_strict: true
+    summary: '{{ .labels.missing }}'`, `invalid summary in receiver "jira"`},
+        {"strict accepts known keys", `
+  - name: jira
+    template_strict: true
+    summary: '{{ .labels.alertname }} on {{ .labels.instance }}'`, ""},
+        {"strict does not apply to the fallback summary", `
+  - name: jira
+    template_strict: true
+    fallback_summary: '{{ .labels.missing }}'`, ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dir := writeFiles(t, map[string]string{ "test.tmpl": `{{ define "test" }}{{ end }}` })
+            path := filepath.Join(dir, "config.yml")
+            if err := ioutil.WriteFile(path, []byte(testConfig(dir, tt.receiver)), 0644); err != nil {
+                t.Fatal(err)
+            }
+
+            _, errs := Load(path)
+            if tt.err == "" && len(errs) > 0 {
+                t.Fatalf("Load() = %v, want no errors", errs)
+            }
+            if tt.err != "" && (len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.err)) {
+                t.Fatalf("Load() = %v, want %q", errs, tt.err)
+            }
+        })
+    }
+}
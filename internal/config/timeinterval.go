@@ -0,0 +1,264 @@
+package config
+
+import (
+    "fmt"
+    "time"
+    "strings"
+    "strconv"
+)
+
+// MuteTimeInterval is a named list of time intervals in the Alertmanager syntax
+type MuteTimeInterval struct {
+    Name             string                  `yaml:"name"`
+    TimeIntervals    []TimeInterval          `yaml:"time_intervals"`
+}
+
+// TimeInterval matches the times within all of its non-empty fields
+type TimeInterval struct {
+    Times            []TimeRange
+    Weekdays         []InclusiveRange
+    DaysOfMonth      []InclusiveRange
+    Months           []InclusiveRange
+    Years            []InclusiveRange
+    Location         *time.Location
+}
+
+// TimeRange is a range of minutes within a day, the end is exclusive
+type TimeRange struct {
+    StartMinute      int
+    EndMinute        int
+}
+
+type InclusiveRange struct {
+    Begin            int
+    End              int
+}
+
+var weekdays = map[string]int{
+    "sunday": 0, "monday": 1, "tuesday": 2, "wednesday": 3, "thursday": 4, "friday": 5, "saturday": 6,
+}
+
+var months = map[string]int{
+    "january": 1, "february": 2, "march": 3, "april": 4, "may": 5, "june": 6,
+    "july": 7, "august": 8, "september": 9, "october": 10, "november": 11, "december": 12,
+}
+
+// parseMinute parses a time of day like 17:30 into minutes from midnight, 24:00 is the end of the day
+func parseMinute(value string) (int, error) {
+    parts := strings.Split(value, ":")
+    if len(parts) != 2 || len(parts[1]) != 2 {
+        return 0, fmt.Errorf("invalid time %q, must be HH:MM", value)
+    }
+    h, err := strconv.Atoi(parts[0])
+    if err != nil {
+        return 0, fmt.Errorf("invalid time %q, must be HH:MM", value)
+    }
+    m, err := strconv.Atoi(parts[1])
+    if err != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+        return 0, fmt.Errorf("invalid time %q, must be between 00:00 and 24:00", value)
+    }
+    return h * 60 + m, nil
+}
+
+// parseRange parses a value or a begin:end range of numbers or names between min and max
+func parseRange(value string, names map[string]int, min, max int) (InclusiveRange, error) {
+    parse := func(v string) (int, error) {
+        v = strings.ToLower(strings.TrimSpace(v))
+        if n, ok := names[v]; ok {
+            return n, nil
+        }
+        n, err := strconv.Atoi(v)
+        if err != nil || n < min || n > max || (min < 0 && n == 0) {
+            return 0, fmt.Errorf("invalid value %q", v)
+        }
+        return n, nil
+    }
+
+    parts := strings.Split(value, ":")
+    if len(parts) > 2 {
+        return InclusiveRange{}, fmt.Errorf("invalid range %q", value)
+    }
+
+    begin, err := parse(parts[0])
+    if err != nil {
+        return InclusiveRange{}, err
+    }
+    end := begin
+    if len(parts) == 2 {
+        if end, err = parse(parts[1]); err != nil {
+            return InclusiveRange{}, err
+        }
+    }
+    if begin > end && (begin < 0) == (end < 0) {
+        return InclusiveRange{}, fmt.Errorf("invalid range %q, begin is after end", value)
+    }
+
+    return InclusiveRange{ Begin: begin, End: end }, nil
+}
+
+// checkDaysOfMonth rejects ranges that are empty in every month once the negative days are resolved,
+// from the end of a month with 28 days for the begin and 31 days for the end
+func checkDaysOfMonth(ranges []InclusiveRange) error {
+    for _, r := range ranges {
+        begin, end := r.Begin, r.End
+        if begin < 0 {
+            begin = 28 + begin + 1
+        }
+        if end < 0 {
+            end = 31 + end + 1
+        }
+        if begin > end {
+            return fmt.Errorf("days_of_month: invalid range %d:%d, begin is after end in every month", r.Begin, r.End)
+        }
+    }
+    return nil
+}
+
+func parseRanges(values []string, names map[string]int, min, max int, field string) ([]InclusiveRange, error) {
+    var ranges []InclusiveRange
+    for _, value := range values {
+        r, err := parseRange(value, names, min, max)
+        if err != nil {
+            return nil, fmt.Errorf("%s: %v", field, err)
+        }
+        ranges = append(ranges, r)
+    }
+    return ranges, nil
+}
+
+func (ti *TimeInterval) UnmarshalYAML(unmarshal func(interface{}) error) error {
+    var raw struct {
+        Times       []struct {
+            StartTime string                 `yaml:"start_time"`
+            EndTime   string                 `yaml:"end_time"`
+        }                                    `yaml:"times"`
+        Weekdays    []string                 `yaml:"weekdays"`
+        DaysOfMonth []string                 `yaml:"days_of_month"`
+        Months      []string                 `yaml:"months"`
+        Years       []string                 `yaml:"years"`
+        Location    string                   `yaml:"location"`
+    }
+    if err := unmarshal(&raw); err != nil {
+        return err
+    }
+
+    for _, t := range raw.Times {
+        start, err := parseMinute(t.StartTime)
+        if err != nil {
+            return fmt.Errorf("times: %v", err)
+        }
+        end, err := parseMinute(t.EndTime)
+        if err != nil {
+            return fmt.Errorf("times: %v", err)
+        }
+        if start >= end {
+            return fmt.Errorf("times: start_time %s must be before end_time %s", t.StartTime, t.EndTime)
+        }
+        ti.Times = append(ti.Times, TimeRange{ StartMinute: start, EndMinute: end })
+    }
+
+    var err error
+    if ti.Weekdays, err = parseRanges(raw.Weekdays, weekdays, 0, 6, "weekdays"); err != nil {
+        return err
+    }
+    if ti.DaysOfMonth, err = parseRanges(raw.DaysOfMonth, nil, -31, 31, "days_of_month"); err != nil {
+        return err
+    }
+    if err = checkDaysOfMonth(ti.DaysOfMonth); err != nil {
+        return err
+    }
+    if ti.Months, err = parseRanges(raw.Months, months, 1, 12, "months"); err != nil {
+        return err
+    }
+    if ti.Years, err = parseRanges(raw.Years, nil, 0, 9999, "years"); err != nil {
+        return err
+    }
+
+    if raw.Location != "" {
+        if ti.Location, err = time.LoadLocation(raw.Location); err != nil {
+            return fmt.Errorf("location: %v", err)
+        }
+    }
+
+    return nil
+}
+
+func inRanges(value int, ranges []InclusiveRange) bool {
+    if len(ranges) == 0 {
+        return true
+    }
+    for _, r := range ranges {
+        if value >= r.Begin && value <= r.End {
+            return true
+        }
+    }
+    return false
+}
+
+// ContainsTime reports whether the time is within the interval, times are UTC unless a location is set
+func (ti TimeInterval) ContainsTime(t time.Time) bool {
+    if ti.Location != nil {
+        t = t.In(ti.Location)
+    } else {
+        t = t.UTC()
+    }
+
+    if len(ti.Times) > 0 {
+        minute := t.Hour() * 60 + t.Minute()
+        in := false
+        for _, r := range ti.Times {
+            if minute >= r.StartMinute && minute < r.EndMinute {
+                in = true
+                break
+            }
+        }
+        if !in {
+            return false
+        }
+    }
+
+    if !inRanges(int(t.Weekday()), ti.Weekdays) {
+        return false
+    }
+
+    if len(ti.DaysOfMonth) > 0 {
+        // Negative days count back from the end of the month
+        last := time.Date(t.Year(), t.Month() + 1, 0, 0, 0, 0, 0, t.Location()).Day()
+        in := false
+        for _, r := range ti.DaysOfMonth {
+            begin, end := r.Begin, r.End
+            if begin < 0 {
+                begin = last + begin + 1
+            }
+            if end < 0 {
+                end = last + end + 1
+            }
+            if t.Day() >= begin && t.Day() <= end {
+                in = true
+                break
+            }
+        }
+        if !in {
+            return false
+        }
+    }
+
+    return inRanges(int(t.Month()), ti.Months) && inRanges(t.Year(), ti.Years)
+}
+
+// MutedBy returns the name of the mute time interval of the receiver the time is within
+func (cfg *Config) MutedBy(rc *Receiver, t time.Time) (string, bool) {
+    for _, name := range rc.MuteTimeIntervals {
+        for _, mti := range cfg.MuteTimeIntervals {
+            if mti.Name != name {
+                continue
+            }
+            for _, ti := range mti.TimeIntervals {
+                if ti.ContainsTime(t) {
+                    return name, true
+                }
+            }
+        }
+    }
+    return "", false
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+    "strings"
+    "testing"
+    "time"
+    "gopkg.in/yaml.v2"
+)
+
+func parseInterval(t *testing.T, text string) (TimeInterval, error) {
+    var ti TimeInterval
+    err := yaml.UnmarshalStrict([]byte(text), &ti)
+    return ti, err
+}
+
+func TestTimeIntervalParse(t *testing.T) {
+    tests := []struct {
+        name     string
+        text     string
+        err      string
+    }{
+        {"times", `times: [{start_time: '08:00', end_time: '24:00'}]`, ""},
+        {"time without minutes", `times: [{start_time: '8', end_time: '10:00'}]`, "must be HH:MM"},
+        {"time after midnight", `times: [{start_time: '08:00', end_time: '24:01'}]`, "between 00:00 and 24:00"},
+        {"start after end", `times: [{start_time: '18:00', end_time: '08:00'}]`, "must be before end_time"},
+        {"weekday names", `weekdays: ['monday:friday', 'Sunday']`, ""},
+        {"weekday numbers", `weekdays: ['1:5']`, ""},
+        {"unknown weekday", `weekdays: ['funday']`, "weekdays: invalid value"},
+        {"weekdays reversed", `weekdays: ['friday:monday']`, "begin is after end"},
+        {"days of month", `days_of_month: ['1:15', '-1', '-7:-1', '25:-1']`, ""},
+        {"day zero", `days_of_month: ['0']`, "invalid value"},
+        {"day out of range", `days_of_month: ['32']`, "invalid value"},
+        {"negative days reversed", `days_of_month: ['-1:-7']`, "begin is after end"},
+        {"last day to first day", `days_of_month: ['-1:1']`, "begin is after end in every month"},
+        {"first day to before the first", `days_of_month: ['5:-31']`, "begin is after end in every month"},
+        {"month names", `months: ['january:march', 'December']`, ""},
+        {"unknown month", `months: ['smarch']`, "months: invalid value"},
+        {"too many colons", `months: ['1:2:3']`, "invalid range"},
+        {"years", `years: ['2020:2030']`, ""},
+        {"location", `location: 'Europe/Berlin'`, ""},
+        {"unknown location", `location: 'Mars/Olympus'`, "location:"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := parseInterval(t, tt.text)
+            if tt.err == "" {
+                if err != nil {
+                    t.Errorf("unexpected error %v", err)
+                }
+                return
+            }
+            if err == nil || !strings.Contains(err.Error(), tt.err) {
+                t.Errorf("got error %v, want %q", err, tt.err)
+            }
+        })
+    }
+}
+
+func TestTimeIntervalContainsTime(t *testing.T) {
+    tests := []struct {
+        name     string
+        text     string
+        time     string
+        want     bool
+    }{
+        {"within times", `times: [{start_time: '08:00', end_time: '18:00'}]`, "2021-03-01T08:00:00Z", true},
+        {"end is exclusive", `times: [{start_time: '08:00', end_time: '18:00'}]`, "2021-03-01T18:00:00Z", false},
+        {"until midnight", `times: [{start_time: '22:00', end_time: '24:00'}]`, "2021-03-01T23:59:00Z", true},
+        {"weekday", `weekdays: ['monday:friday']`, "2021-03-05T12:00:00Z", true},
+        {"weekend", `weekdays: ['monday:friday']`, "2021-03-06T12:00:00Z", false},
+        {"last day of february", `days_of_month: ['-1']`, "2021-02-28T12:00:00Z", true},
+        {"not the last day", `days_of_month: ['-1']`, "2021-03-30T12:00:00Z", false},
+        {"last week of month", `days_of_month: ['-7:-1']`, "2021-04-24T12:00:00Z", true},
+        {"day to end of month", `days_of_month: ['25:-1']`, "2021-01-31T12:00:00Z", true},
+        {"month range", `months: ['january:march']`, "2021-03-31T12:00:00Z", true},
+        {"outside month range", `months: ['january:march']`, "2021-04-01T12:00:00Z", false},
+        {"year", `years: ['2021']`, "2021-06-01T12:00:00Z", true},
+        {"other year", `years: ['2021']`, "2022-06-01T12:00:00Z", false},
+        {"location shifts the day", "weekdays: ['saturday']\nlocation: 'Asia/Tokyo'", "2021-03-05T20:00:00Z", true},
+        {"location shifts the time", "times: [{start_time: '08:00', end_time: '09:00'}]\nlocation: 'Europe/Berlin'", "2021-03-01T07:30:00Z", true},
+        {"utc without location", "times: [{start_time: '08:00', end_time: '09:00'}]", "2021-03-01T07:30:00Z", false},
+        {"all fields", "times: [{start_time: '02:00', end_time: '04:00'}]\ndays_of_month: ['-1']\nmonths: ['january:march']", "2021-03-31T03:00:00Z", true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ti, err := parseInterval(t, tt.text)
+            if err != nil {
+                t.Fatal(err)
+            }
+            at, err := time.Parse(time.RFC3339, tt.time)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got := ti.ContainsTime(at); got != tt.want {
+                t.Errorf("ContainsTime(%s) = %v, want %v", tt.time, got, tt.want)
+            }
+        })
+    }
+}
@@ -9,11 +9,23 @@ import (
 
 type DbClient interface {
     CreateTables() error
-    LoadIssue(mgrp_id string) (config.Issue, error)
+    Ping() error
+    LoadIssue(group_id string) (config.Issue, bool, error)
     LoadIssues() ([]config.Issue, error)
     SaveIssue(issue config.Issue) error
     UpdateStatus(group_id, status_id, status_name string) error
+    UpdateEscalation(issue_key string, step int) error
+    UpdateSilence(group_id, silence_id string, ends int64) error
     DeleteIssue(group_id string) error
+    SaveEvent(event config.Event) error
+    LoadEvents(filter config.EventFilter) ([]config.Event, error)
+    DeleteEvents(before int64) (int64, error)
+    SaveDryRun(dr config.DryRun) error
+    LoadDryRuns(filter config.DryRunFilter) ([]config.DryRun, error)
+    DeleteDryRuns(before int64) (int64, error)
+    SaveSilence(sl config.Silence) (int64, error)
+    LoadSilences(filter config.SilenceFilter) ([]config.Silence, error)
+    ExpireSilence(id int64) error
     Close() error
 }
 
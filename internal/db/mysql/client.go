@@ -1,8 +1,11 @@
 package mysql
 
 import (
+    "fmt"
     "time"
+    "strings"
     "database/sql"
+    "encoding/json"
     _ "github.com/go-sql-driver/mysql"
     "github.com/ltkh/jiramanager/internal/config"
 )
@@ -26,31 +29,140 @@ func (db *Client) Close() error {
 	return nil
 }
 
+func (db *Client) Ping() error {
+    return db.client.Ping()
+}
+
+// Tables of config/dbase.sql, created when missing
+var tables = []string{
+    `create table if not exists issues (
+      group_id      varchar(50) not null,
+      status_id     varchar(50) default '',
+      status_name   varchar(100) default '',
+      issue_id      varchar(50),
+      issue_key     varchar(50),
+      issue_self    varchar(1500),
+      created       bigint(20) default 0,
+      updated       bigint(20) default 0,
+      template      varchar(250) default '',
+      receiver      varchar(250) default '',
+      link_key      varchar(1500) default '',
+      escalation    int default 0,
+      labels        text,
+      silence_id    varchar(50) default '',
+      silence_ends  bigint(20) default 0,
+      unique key IDX_issues_group_id (group_id)
+    ) engine InnoDB default charset=utf8mb4 collate=utf8mb4_unicode_ci`,
+    `create table if not exists issue_events (
+      id            bigint(20) not null auto_increment,
+      group_id      varchar(50),
+      issue_key     varchar(50) default '',
+      fingerprint   varchar(50) default '',
+      event         varchar(50),
+      details       varchar(1500) default '',
+      created       bigint(20) default 0,
+      primary key (id),
+      key IDX_issue_events_group_id (group_id),
+      key IDX_issue_events_issue_key (issue_key),
+      key IDX_issue_events_created (created)
+    ) engine InnoDB default charset=utf8mb4 collate=utf8mb4_unicode_ci`,
+    `create table if not exists dry_runs (
+      id            bigint(20) not null auto_increment,
+      group_id      varchar(50),
+      receiver      varchar(250) default '',
+      fingerprint   varchar(50) default '',
+      payload       mediumtext,
+      created       bigint(20) default 0,
+      primary key (id),
+      key IDX_dry_runs_group_id (group_id),
+      key IDX_dry_runs_created (created)
+    ) engine InnoDB default charset=utf8mb4 collate=utf8mb4_unicode_ci`,
+    `create table if not exists silences (
+      id            bigint(20) not null auto_increment,
+      matchers      text,
+      created_by    varchar(250) default '',
+      comment       varchar(1500) default '',
+      ends_at       bigint(20) default 0,
+      created       bigint(20) default 0,
+      primary key (id),
+      key IDX_silences_ends_at (ends_at)
+    ) engine InnoDB default charset=utf8mb4 collate=utf8mb4_unicode_ci`,
+}
+
+// Columns added to the issues table after the first release
+var addedColumns = []struct {
+    name         string
+    definition   string
+}{
+    {"receiver", "varchar(250) default ''"},
+    {"link_key", "varchar(1500) default ''"},
+    {"escalation", "int default 0"},
+    {"labels", "text"},
+    {"silence_id", "varchar(50) default ''"},
+    {"silence_ends", "bigint(20) default 0"},
+}
+
 func (db *Client) CreateTables() error {
+    for _, table := range tables {
+        if _, err := db.client.Exec(table); err != nil {
+            return err
+        }
+    }
+
+    for _, column := range addedColumns {
+        if err := db.addColumn("issues", column.name, column.definition); err != nil {
+            return err
+        }
+    }
+
     return nil
 }
 
-func (db *Client) LoadIssue(group_id string) (config.Issue, error) {
+// addColumn adds a column to a table created by an earlier release
+func (db *Client) addColumn(table, column, definition string) error {
+    var count int
+    err := db.client.QueryRow("select count(*) from information_schema.columns where table_schema = database() and table_name = ? and column_name = ?", table, column).Scan(&count)
+    if err != nil {
+        return err
+    }
+    if count > 0 {
+        return nil
+    }
+
+    _, err = db.client.Exec(fmt.Sprintf("alter table %s add column %s %s", table, column, definition))
+    return err
+}
+
+func (db *Client) LoadIssue(group_id string) (config.Issue, bool, error) {
     var issue config.Issue
+    var labels sql.NullString
 
-    stmt, err := db.client.Prepare("select group_id,status_id,status_name,issue_id,issue_key,issue_self from issues where group_id = ?")
+    stmt, err := db.client.Prepare("select group_id,status_id,status_name,issue_id,issue_key,issue_self,created,updated,template,receiver,link_key,escalation,labels,silence_id,silence_ends from issues where group_id = ?")
     if err != nil {
-        return issue, err
+        return issue, false, err
     }
     defer stmt.Close()
 
-    err = stmt.QueryRow(group_id).Scan(&issue.GroupId, &issue.StatusId, &issue.StatusName, &issue.IssueId, &issue.IssueKey, &issue.IssueSelf)
+    err = stmt.QueryRow(group_id).Scan(&issue.GroupId, &issue.StatusId, &issue.StatusName, &issue.IssueId, &issue.IssueKey, &issue.IssueSelf, &issue.Created, &issue.Updated, &issue.Template, &issue.Receiver, &issue.LinkKey, &issue.Escalation, &labels, &issue.SilenceId, &issue.SilenceEnds)
+    if err == sql.ErrNoRows {
+        return issue, false, nil
+    }
     if err != nil {
-        return issue, nil
+        return issue, false, err
+    }
+    if labels.String != "" {
+        if err := json.Unmarshal([]byte(labels.String), &issue.Labels); err != nil {
+            return issue, false, err
+        }
     }
 
-      return issue, nil
+    return issue, true, nil
 }
 
 func (db *Client) LoadIssues() ([]config.Issue, error) {
     var result []config.Issue
 
-    rows, err := db.client.Query("select group_id,status_id,status_name,issue_id,issue_key,issue_self,created,updated from issues")
+    rows, err := db.client.Query("select group_id,status_id,status_name,issue_id,issue_key,issue_self,created,updated,template,receiver,link_key,escalation,labels,silence_id,silence_ends from issues")
     if err != nil {
         return nil, err
     }
@@ -58,10 +170,16 @@ func (db *Client) LoadIssues() ([]config.Issue, error) {
 
     for rows.Next() {
         var issue config.Issue
-        err := rows.Scan(&issue.GroupId, &issue.StatusId, &issue.StatusName, &issue.IssueId, &issue.IssueKey, &issue.IssueSelf, &issue.Created, &issue.Updated)
+        var labels sql.NullString
+        err := rows.Scan(&issue.GroupId, &issue.StatusId, &issue.StatusName, &issue.IssueId, &issue.IssueKey, &issue.IssueSelf, &issue.Created, &issue.Updated, &issue.Template, &issue.Receiver, &issue.LinkKey, &issue.Escalation, &labels, &issue.SilenceId, &issue.SilenceEnds)
         if err != nil {
             return nil, err
         }
+        if labels.String != "" {
+            if err := json.Unmarshal([]byte(labels.String), &issue.Labels); err != nil {
+                return nil, err
+            }
+        }
         result = append(result, issue) 
     }
 
@@ -69,14 +187,21 @@ func (db *Client) LoadIssues() ([]config.Issue, error) {
 }
 
 func (db *Client) SaveIssue(issue config.Issue) error {
-    stmt, err := db.client.Prepare("replace into issues (group_id,status_id,status_name,issue_id,issue_key,issue_self,created,updated,template) values (?,?,?,?,?,?,?,?,?)")
+    stmt, err := db.client.Prepare("replace into issues (group_id,status_id,status_name,issue_id,issue_key,issue_self,created,updated,template,receiver,link_key,escalation,labels,silence_id,silence_ends) values (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)")
     if err != nil {
         return err
     }
     defer stmt.Close()
 
+    var labels []byte
+    if len(issue.Labels) > 0 {
+        if labels, err = json.Marshal(issue.Labels); err != nil {
+            return err
+        }
+    }
+
     utc := time.Now().UTC().Unix()
-    _, err = stmt.Exec(issue.GroupId, issue.StatusId, issue.StatusName, issue.IssueId, issue.IssueKey, issue.IssueSelf, utc, utc, issue.Template)
+    _, err = stmt.Exec(issue.GroupId, issue.StatusId, issue.StatusName, issue.IssueId, issue.IssueKey, issue.IssueSelf, utc, utc, issue.Template, issue.Receiver, issue.LinkKey, issue.Escalation, string(labels), issue.SilenceId, issue.SilenceEnds)
     if err != nil {
         return err
     }
@@ -101,6 +226,36 @@ func (db *Client) UpdateStatus(group_id, status_id, status_name string) error {
 
 }
 
+func (db *Client) UpdateEscalation(issue_key string, step int) error {
+    stmt, err := db.client.Prepare("update issues set escalation = ? where issue_key = ?")
+    if err != nil {
+        return err
+    }
+    defer stmt.Close()
+
+    _, err = stmt.Exec(step, issue_key)
+    if err != nil {
+        return err
+    }
+
+    return nil
+}
+
+func (db *Client) UpdateSilence(group_id, silence_id string, ends int64) error {
+    stmt, err := db.client.Prepare("update issues set silence_id = ?, silence_ends = ? where group_id = ?")
+    if err != nil {
+        return err
+    }
+    defer stmt.Close()
+
+    _, err = stmt.Exec(silence_id, ends, group_id)
+    if err != nil {
+        return err
+    }
+
+    return nil
+}
+
 func (db *Client) DeleteIssue(group_id string) error {
     stmt, err := db.client.Prepare("delete from issues where group_id = ?")
     if err != nil {
@@ -114,4 +269,248 @@ func (db *Client) DeleteIssue(group_id string) error {
     }
 
     return nil
-}
\ No newline at end of file
+}
+
+func (db *Client) SaveEvent(event config.Event) error {
+    stmt, err := db.client.Prepare("insert into issue_events (group_id,issue_key,fingerprint,event,details,created) values (?,?,?,?,?,?)")
+    if err != nil {
+        return err
+    }
+    defer stmt.Close()
+
+    if event.Created == 0 {
+        event.Created = time.Now().UTC().Unix()
+    }
+    _, err = stmt.Exec(event.GroupId, event.IssueKey, event.Fingerprint, event.Event, event.Details, event.Created)
+    if err != nil {
+        return err
+    }
+
+    return nil
+}
+
+func (db *Client) LoadEvents(filter config.EventFilter) ([]config.Event, error) {
+    var result []config.Event
+    var where []string
+    var args []interface{}
+
+    if filter.GroupId != "" {
+        where = append(where, "group_id = ?")
+        args = append(args, filter.GroupId)
+    }
+    if filter.IssueKey != "" {
+        where = append(where, "issue_key = ?")
+        args = append(args, filter.IssueKey)
+    }
+    if filter.From > 0 {
+        where = append(where, "created >= ?")
+        args = append(args, filter.From)
+    }
+    if filter.To > 0 {
+        where = append(where, "created <= ?")
+        args = append(args, filter.To)
+    }
+
+    query := "select id,group_id,issue_key,fingerprint,event,details,created from issue_events"
+    if len(where) > 0 {
+        query = query + " where " + strings.Join(where, " and ")
+    }
+    query = query + " order by id"
+    if filter.Limit > 0 {
+        query = query + " limit ? offset ?"
+        args = append(args, filter.Limit, filter.Offset)
+    }
+
+    rows, err := db.client.Query(query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var event config.Event
+        err := rows.Scan(&event.Id, &event.GroupId, &event.IssueKey, &event.Fingerprint, &event.Event, &event.Details, &event.Created)
+        if err != nil {
+            return nil, err
+        }
+        result = append(result, event)
+    }
+
+    return result, nil
+}
+
+func (db *Client) DeleteEvents(before int64) (int64, error) {
+    stmt, err := db.client.Prepare("delete from issue_events where created < ?")
+    if err != nil {
+        return 0, err
+    }
+    defer stmt.Close()
+
+    res, err := stmt.Exec(before)
+    if err != nil {
+        return 0, err
+    }
+
+    return res.RowsAffected()
+}
+
+func (db *Client) SaveDryRun(dr config.DryRun) error {
+    stmt, err := db.client.Prepare("insert into dry_runs (group_id,receiver,fingerprint,payload,created) values (?,?,?,?,?)")
+    if err != nil {
+        return err
+    }
+    defer stmt.Close()
+
+    if dr.Created == 0 {
+        dr.Created = time.Now().UTC().Unix()
+    }
+    _, err = stmt.Exec(dr.GroupId, dr.Receiver, dr.Fingerprint, string(dr.Payload), dr.Created)
+    if err != nil {
+        return err
+    }
+
+    return nil
+}
+
+func (db *Client) LoadDryRuns(filter config.DryRunFilter) ([]config.DryRun, error) {
+    var result []config.DryRun
+    var where []string
+    var args []interface{}
+
+    if filter.GroupId != "" {
+        where = append(where, "group_id = ?")
+        args = append(args, filter.GroupId)
+    }
+    if filter.Receiver != "" {
+        where = append(where, "receiver = ?")
+        args = append(args, filter.Receiver)
+    }
+    if filter.From > 0 {
+        where = append(where, "created >= ?")
+        args = append(args, filter.From)
+    }
+    if filter.To > 0 {
+        where = append(where, "created <= ?")
+        args = append(args, filter.To)
+    }
+
+    query := "select id,group_id,receiver,fingerprint,payload,created from dry_runs"
+    if len(where) > 0 {
+        query = query + " where " + strings.Join(where, " and ")
+    }
+    query = query + " order by id"
+
+    rows, err := db.client.Query(query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var dr config.DryRun
+        var payload string
+        err := rows.Scan(&dr.Id, &dr.GroupId, &dr.Receiver, &dr.Fingerprint, &payload, &dr.Created)
+        if err != nil {
+            return nil, err
+        }
+        dr.Payload = json.RawMessage(payload)
+        result = append(result, dr)
+    }
+
+    return result, nil
+}
+
+func (db *Client) DeleteDryRuns(before int64) (int64, error) {
+    stmt, err := db.client.Prepare("delete from dry_runs where created < ?")
+    if err != nil {
+        return 0, err
+    }
+    defer stmt.Close()
+
+    res, err := stmt.Exec(before)
+    if err != nil {
+        return 0, err
+    }
+
+    return res.RowsAffected()
+}
+
+func (db *Client) SaveSilence(sl config.Silence) (int64, error) {
+    stmt, err := db.client.Prepare("insert into silences (matchers,created_by,comment,ends_at,created) values (?,?,?,?,?)")
+    if err != nil {
+        return 0, err
+    }
+    defer stmt.Close()
+
+    matchers, err := json.Marshal(sl.Matchers)
+    if err != nil {
+        return 0, err
+    }
+    if sl.Created == 0 {
+        sl.Created = time.Now().UTC().Unix()
+    }
+    res, err := stmt.Exec(string(matchers), sl.CreatedBy, sl.Comment, sl.EndsAt, sl.Created)
+    if err != nil {
+        return 0, err
+    }
+
+    return res.LastInsertId()
+}
+
+func (db *Client) LoadSilences(filter config.SilenceFilter) ([]config.Silence, error) {
+    var result []config.Silence
+    var where []string
+    var args []interface{}
+
+    if filter.Id > 0 {
+        where = append(where, "id = ?")
+        args = append(args, filter.Id)
+    }
+    if filter.Active {
+        where = append(where, "ends_at > ?")
+        args = append(args, time.Now().UTC().Unix())
+    }
+
+    query := "select id,matchers,created_by,comment,ends_at,created from silences"
+    if len(where) > 0 {
+        query = query + " where " + strings.Join(where, " and ")
+    }
+    query = query + " order by id"
+
+    rows, err := db.client.Query(query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var sl config.Silence
+        var matchers string
+        err := rows.Scan(&sl.Id, &matchers, &sl.CreatedBy, &sl.Comment, &sl.EndsAt, &sl.Created)
+        if err != nil {
+            return nil, err
+        }
+        if err := json.Unmarshal([]byte(matchers), &sl.Matchers); err != nil {
+            return nil, err
+        }
+        result = append(result, sl)
+    }
+
+    return result, nil
+}
+
+func (db *Client) ExpireSilence(id int64) error {
+    stmt, err := db.client.Prepare("update silences set ends_at = ? where id = ? and ends_at > ?")
+    if err != nil {
+        return err
+    }
+    defer stmt.Close()
+
+    now := time.Now().UTC().Unix()
+    _, err = stmt.Exec(now, id, now)
+    if err != nil {
+        return err
+    }
+
+    return nil
+}
@@ -0,0 +1,110 @@
+package mysql
+
+import (
+    "errors"
+    "testing"
+    "database/sql"
+    "github.com/DATA-DOG/go-sqlmock"
+)
+
+var issueColumns = []string{
+    "group_id", "status_id", "status_name", "issue_id", "issue_key", "issue_self", "created", "updated",
+    "template", "receiver", "link_key", "escalation", "labels", "silence_id", "silence_ends",
+}
+
+func newMockClient(t *testing.T) (*Client, sqlmock.Sqlmock) {
+    conn, mock, err := sqlmock.New()
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    return &Client{ client: conn }, mock
+}
+
+func TestLoadIssue(t *testing.T) {
+    tests := []struct {
+        name     string
+        rows     *sqlmock.Rows
+        err      error
+        found    bool
+        wantErr  bool
+    }{
+        {
+            name:   "found",
+            rows:   sqlmock.NewRows(issueColumns).AddRow("g1", "1", "Open", "1001", "TEST-1", "http://localhost", 1, 2, "", "jira", "", 0, `{"alertname":"up"}`, "", 0),
+            found:  true,
+        },
+        {
+            name:   "not found",
+            err:    sql.ErrNoRows,
+        },
+        {
+            name:    "database error",
+            err:     errors.New("connection reset"),
+            wantErr: true,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            db, mock := newMockClient(t)
+            query := mock.ExpectPrepare("select .+ from issues where group_id = ?").ExpectQuery().WithArgs("g1")
+            if tt.rows != nil {
+                query.WillReturnRows(tt.rows)
+            } else {
+                query.WillReturnError(tt.err)
+            }
+
+            issue, found, err := db.LoadIssue("g1")
+            if (err != nil) != tt.wantErr || found != tt.found {
+                t.Fatalf("LoadIssue() = %v, %v, want found %v, error %v", found, err, tt.found, tt.wantErr)
+            }
+            if tt.found && (issue.IssueKey != "TEST-1" || issue.Labels["alertname"] != "up") {
+                t.Errorf("LoadIssue() = %+v", issue)
+            }
+            if err := mock.ExpectationsWereMet(); err != nil {
+                t.Error(err)
+            }
+        })
+    }
+}
+
+func TestCreateTablesAddsMissingColumns(t *testing.T) {
+    db, mock := newMockClient(t)
+
+    for range tables {
+        mock.ExpectExec("create table if not exists").WillReturnResult(sqlmock.NewResult(0, 0))
+    }
+    for _, column := range addedColumns {
+        count := 1
+        if column.name == "labels" {
+            count = 0
+        }
+        mock.ExpectQuery("select count\\(\\*\\) from information_schema.columns").
+            WithArgs("issues", column.name).
+            WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+        if count == 0 {
+            mock.ExpectExec("alter table issues add column labels text").WillReturnResult(sqlmock.NewResult(0, 0))
+        }
+    }
+
+    if err := db.CreateTables(); err != nil {
+        t.Fatal(err)
+    }
+    if err := mock.ExpectationsWereMet(); err != nil {
+        t.Error(err)
+    }
+}
+
+func TestDeleteDryRuns(t *testing.T) {
+    db, mock := newMockClient(t)
+    mock.ExpectPrepare("delete from dry_runs where created < ?").ExpectExec().WithArgs(250).WillReturnResult(sqlmock.NewResult(0, 2))
+
+    n, err := db.DeleteDryRuns(250)
+    if err != nil || n != 2 {
+        t.Fatalf("DeleteDryRuns(250) = %d, %v, want 2 removed", n, err)
+    }
+    if err := mock.ExpectationsWereMet(); err != nil {
+        t.Error(err)
+    }
+}
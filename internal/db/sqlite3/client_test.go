@@ -0,0 +1,169 @@
+package sqlite3
+
+import (
+    "os"
+    "fmt"
+    "testing"
+    "io/ioutil"
+    "path/filepath"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+func newTestClient(t *testing.T) *Client {
+    dir, err := ioutil.TempDir("", "jiramanager")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { os.RemoveAll(dir) })
+
+    db, err := NewClient(&config.DB{ Client: "sqlite3", ConnString: filepath.Join(dir, "test.db") })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := db.CreateTables(); err != nil {
+        t.Fatal(err)
+    }
+    return db
+}
+
+func TestLoadIssue(t *testing.T) {
+    db := newTestClient(t)
+    defer db.Close()
+
+    issue := config.Issue{
+        GroupId:    "g1",
+        IssueId:    "1001",
+        IssueKey:   "TEST-1",
+        IssueSelf:  "http://localhost/rest/api/2/issue/1001",
+        Receiver:   "jira",
+        Labels:     map[string]string{"alertname": "up"},
+    }
+    if err := db.SaveIssue(issue); err != nil {
+        t.Fatal(err)
+    }
+
+    found, ok, err := db.LoadIssue("g1")
+    if err != nil || !ok {
+        t.Fatalf("LoadIssue(g1) = %v, %v, want found", ok, err)
+    }
+    if found.IssueKey != "TEST-1" || found.Receiver != "jira" || found.Labels["alertname"] != "up" {
+        t.Errorf("LoadIssue(g1) = %+v", found)
+    }
+
+    if _, ok, err := db.LoadIssue("missing"); err != nil || ok {
+        t.Errorf("LoadIssue(missing) = %v, %v, want not found without error", ok, err)
+    }
+
+    db.client.Close()
+    if _, ok, err := db.LoadIssue("g1"); err == nil || ok {
+        t.Errorf("LoadIssue on a closed database = %v, %v, want an error", ok, err)
+    }
+}
+
+func TestCreateTablesIndexes(t *testing.T) {
+    db := newTestClient(t)
+    defer db.Close()
+
+    // Creating the tables of an existing database keeps the indexes
+    if err := db.CreateTables(); err != nil {
+        t.Fatal(err)
+    }
+
+    rows, err := db.client.Query("select name from sqlite_master where type = 'index' and tbl_name = 'issue_events'")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer rows.Close()
+    indexes := map[string]bool{}
+    for rows.Next() {
+        var name string
+        if err := rows.Scan(&name); err != nil {
+            t.Fatal(err)
+        }
+        indexes[name] = true
+    }
+    for _, name := range []string{"IDX_issue_events_group_id", "IDX_issue_events_issue_key", "IDX_issue_events_created"} {
+        if !indexes[name] {
+            t.Errorf("index %s of issue_events is missing, found %v", name, indexes)
+        }
+    }
+}
+
+func TestLoadEvents(t *testing.T) {
+    db := newTestClient(t)
+    defer db.Close()
+
+    events := []config.Event{
+        { GroupId: "g1", IssueKey: "TEST-1", Event: config.EventCreated, Created: 100 },
+        { GroupId: "g1", IssueKey: "TEST-1", Event: config.EventDeduplicated, Created: 200 },
+        { GroupId: "g2", IssueKey: "TEST-2", Event: config.EventCreated, Created: 300 },
+        { GroupId: "g2", IssueKey: "TEST-2", Event: config.EventResolved, Created: 400 },
+        { GroupId: "g3", Event: config.EventSilenced, Created: 500 },
+    }
+    for _, event := range events {
+        if err := db.SaveEvent(event); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    tests := []struct {
+        name     string
+        filter   config.EventFilter
+        want     []int64
+    }{
+        {"all", config.EventFilter{}, []int64{100, 200, 300, 400, 500}},
+        {"group_id", config.EventFilter{ GroupId: "g2" }, []int64{300, 400}},
+        {"issue_key", config.EventFilter{ IssueKey: "TEST-1" }, []int64{100, 200}},
+        {"from", config.EventFilter{ From: 300 }, []int64{300, 400, 500}},
+        {"to", config.EventFilter{ To: 200 }, []int64{100, 200}},
+        {"from and to", config.EventFilter{ From: 200, To: 400 }, []int64{200, 300, 400}},
+        {"combined", config.EventFilter{ GroupId: "g2", From: 350 }, []int64{400}},
+        {"no match", config.EventFilter{ GroupId: "missing" }, nil},
+        {"limit", config.EventFilter{ Limit: 2 }, []int64{100, 200}},
+        {"limit and offset", config.EventFilter{ Limit: 2, Offset: 2 }, []int64{300, 400}},
+        {"last page", config.EventFilter{ Limit: 2, Offset: 4 }, []int64{500}},
+        {"offset past the end", config.EventFilter{ Limit: 2, Offset: 10 }, nil},
+        {"filtered page", config.EventFilter{ From: 200, Limit: 2, Offset: 1 }, []int64{300, 400}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            result, err := db.LoadEvents(tt.filter)
+            if err != nil {
+                t.Fatal(err)
+            }
+            var got []int64
+            for _, event := range result {
+                got = append(got, event.Created)
+            }
+            if len(got) != len(tt.want) {
+                t.Fatalf("LoadEvents(%+v) = %v, want %v", tt.filter, got, tt.want)
+            }
+            for i := range got {
+                if got[i] != tt.want[i] {
+                    t.Fatalf("LoadEvents(%+v) = %v, want %v", tt.filter, got, tt.want)
+                }
+            }
+        })
+    }
+}
+
+func TestDeleteDryRuns(t *testing.T) {
+    db := newTestClient(t)
+    defer db.Close()
+
+    for i, created := range []int64{100, 200, 300} {
+        dr := config.DryRun{ GroupId: fmt.Sprintf("g%d", i), Receiver: "jira", Payload: []byte(`{}`), Created: created }
+        if err := db.SaveDryRun(dr); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    n, err := db.DeleteDryRuns(250)
+    if err != nil || n != 2 {
+        t.Fatalf("DeleteDryRuns(250) = %d, %v, want 2 removed", n, err)
+    }
+    runs, err := db.LoadDryRuns(config.DryRunFilter{})
+    if err != nil || len(runs) != 1 || runs[0].Created != 300 {
+        t.Errorf("LoadDryRuns() after DeleteDryRuns = %+v, %v, want the dry run created at 300", runs, err)
+    }
+}
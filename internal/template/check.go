@@ -0,0 +1,68 @@
+package template
+
+import (
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// Undefined parses the provided text and returns the names of the templates it references,
+// directly or through other templates, that are not defined in the template set.
+func (t *Template) Undefined(text string) ([]string, error) {
+	if !strings.Contains(text, "{{") {
+		return nil, nil
+	}
+
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err = tmpl.New("").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var missing []string
+	var walk func(node parse.Node)
+
+	visit := func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		if tt := tmpl.Lookup(name); tt != nil && tt.Tree != nil {
+			walk(tt.Tree.Root)
+			return
+		}
+		missing = append(missing, name)
+	}
+
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+			case *parse.ListNode:
+				if n == nil {
+					return
+				}
+				for _, child := range n.Nodes {
+					walk(child)
+				}
+			case *parse.IfNode:
+				walk(n.List)
+				walk(n.ElseList)
+			case *parse.RangeNode:
+				walk(n.List)
+				walk(n.ElseList)
+			case *parse.WithNode:
+				walk(n.List)
+				walk(n.ElseList)
+			case *parse.TemplateNode:
+				visit(n.Name)
+		}
+	}
+
+	walk(tmpl.Tree.Root)
+
+	sort.Strings(missing)
+	return missing, nil
+}
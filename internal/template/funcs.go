@@ -2,12 +2,17 @@ package template
 
 import (
 	"fmt"
+	"sync"
+	"context"
 	"reflect"
 	"regexp"
 	"strings"
 	"sort"
 	"net"
+	"net/url"
+	"time"
 	"strconv"
+	"encoding/json"
 )
 
 func toInt(i interface{}) (int64, error) {
@@ -72,43 +77,423 @@ func regexReplaceAll(re, pl, s string) (string, error) {
 	return compiled.ReplaceAllString(s, pl), nil
 }
 
-func LookupIP(data string) []string {
-	ips, err := net.LookupIP(data)
-	if err != nil {
-		return nil
-	}
-	// "Cast" IPs into strings and sort the array
-	ipStrings := make([]string, len(ips))
+type dnsEntry struct {
+	ips          []string
+	expires      time.Time
+}
+
+// Maximum number of cached DNS lookups
+var dnsCacheSize = 10000
 
-	for i, ip := range ips {
-		ipStrings[i] = ip.String()
+// Results of DNS lookups shared by all templates
+var dnsCache = struct {
+	sync.Mutex
+	entries      map[string]dnsEntry
+}{entries: make(map[string]dnsEntry)}
+
+// cacheDNS stores the lookup result, expired entries are dropped when the cache is full
+// and then the entry expiring first.
+func cacheDNS(host string, ips []string, now time.Time, ttl time.Duration) {
+	dnsCache.Lock()
+	defer dnsCache.Unlock()
+
+	if _, ok := dnsCache.entries[host]; !ok && len(dnsCache.entries) >= dnsCacheSize {
+		var first string
+		for h, entry := range dnsCache.entries {
+			if !now.Before(entry.expires) {
+				delete(dnsCache.entries, h)
+				continue
+			}
+			if first == "" || entry.expires.Before(dnsCache.entries[first].expires) {
+				first = h
+			}
+		}
+		if len(dnsCache.entries) >= dnsCacheSize {
+			delete(dnsCache.entries, first)
+		}
 	}
-	sort.Strings(ipStrings)
-	return ipStrings
+	dnsCache.entries[host] = dnsEntry{ips: ips, expires: now.Add(ttl)}
 }
 
+// lookupIP resolves the host with the context deadline and caches the result for ttl.
+func lookupIP(ctx context.Context, data string, ttl time.Duration) []string {
+	if ttl > 0 {
+		dnsCache.Lock()
+		entry, ok := dnsCache.entries[data]
+		dnsCache.Unlock()
+		if ok && time.Now().Before(entry.expires) {
+			return entry.ips
+		}
+	}
 
-func LookupIPV6(data string) []string {
-	var addresses []string
-	for _, ip := range LookupIP(data) {
-		if strings.Contains(ip, ":") {
-			addresses = append(addresses, ip)
+	var ipStrings []string
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, data)
+	if err == nil {
+		// "Cast" IPs into strings and sort the array
+		ipStrings = make([]string, len(addrs))
+		for i, addr := range addrs {
+			ipStrings[i] = addr.IP.String()
 		}
+		sort.Strings(ipStrings)
 	}
-	return addresses
+
+	// Timeouts are not cached, the next execution tries again
+	if ttl > 0 && ctx.Err() == nil {
+		cacheDNS(data, ipStrings, time.Now(), ttl)
+	}
+
+	return ipStrings
 }
 
-func LookupIPV4(data string) []string {
+func filterIPs(ips []string, sep string) []string {
 	var addresses []string
-	for _, ip := range LookupIP(data) {
-		if strings.Contains(ip, ".") {
+	for _, ip := range ips {
+		if strings.Contains(ip, sep) {
 			addresses = append(addresses, ip)
 		}
 	}
 	return addresses
 }
 
+func LookupIP(data string) []string {
+	return lookupIP(context.Background(), data, 0)
+}
+
+func LookupIPV6(data string) []string {
+	return filterIPs(LookupIP(data), ":")
+}
+
+func LookupIPV4(data string) []string {
+	return filterIPs(LookupIP(data), ".")
+}
+
 func strQuote(data string) (string, error) {
 	s := strconv.Quote(data)
 	return s[1:len(s)-1], nil
-}
\ No newline at end of file
+}
+
+// toString converts a scalar template value to its string representation.
+func toString(i interface{}) string {
+	switch v := i.(type) {
+		case nil:
+			return ""
+		case string:
+			return v
+		case []byte:
+			return string(v)
+		case fmt.Stringer:
+			return v.String()
+	}
+	return fmt.Sprintf("%v", i)
+}
+
+// toFloatValue is toFloat that also accepts numeric strings.
+func toFloatValue(i interface{}) (float64, error) {
+	if s, ok := i.(string); ok {
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	}
+	return toFloat(i)
+}
+
+// toStrings converts a slice of any type to a slice of strings.
+func toStrings(i interface{}) ([]string, error) {
+	if i == nil {
+		return nil, nil
+	}
+	if s, ok := i.([]string); ok {
+		return s, nil
+	}
+	iv := reflect.ValueOf(i)
+	if iv.Kind() != reflect.Slice && iv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("unknown type - %T", i)
+	}
+	result := make([]string, iv.Len())
+	for n := 0; n < iv.Len(); n++ {
+		result[n] = toString(iv.Index(n).Interface())
+	}
+	return result, nil
+}
+
+// toTime converts RFC3339 strings, unix timestamps and time.Time values to time.Time.
+func toTime(i interface{}) (time.Time, error) {
+	switch v := i.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return t, nil
+	}
+	f, err := toFloat(i)
+	if err != nil {
+		return time.Time{}, err
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f - float64(sec)) * 1e9)).UTC(), nil
+}
+
+// joinFunc concatenates the elements of a list with the separator: {{ join ", " .list }}.
+func joinFunc(sep string, items interface{}) (string, error) {
+	list, err := toStrings(items)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(list, sep), nil
+}
+
+// splitFunc slices s into all substrings separated by sep: {{ split "," .labels.hosts }}.
+func splitFunc(sep, s string) []string {
+	return strings.Split(s, sep)
+}
+
+// defaultFunc returns def when value is empty or missing: {{ .labels.team | default "ops" }}.
+func defaultFunc(def interface{}, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+	iv := reflect.ValueOf(value)
+	switch iv.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			if iv.Len() == 0 {
+				return def
+			}
+		case reflect.Ptr, reflect.Interface:
+			if iv.IsNil() {
+				return def
+			}
+	}
+	return value
+}
+
+// truncate shortens s to at most n characters, the last three replaced by "..." when
+// the text was cut: {{ truncate 255 .annotations.summary }}.
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n < 0 || len(runes) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+// dateFunc formats a time with the Go layout: {{ date "2006-01-02 15:04" .startsAt }}.
+func dateFunc(layout string, value interface{}) (string, error) {
+	t, err := toTime(value)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(layout), nil
+}
+
+// since returns the time elapsed since the given time: {{ since .startsAt | humanizeDuration }}.
+func since(value interface{}) (time.Duration, error) {
+	t, err := toTime(value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(t), nil
+}
+
+// humanizeDuration formats a duration or a number of seconds as "1d 2h 3m 4s".
+func humanizeDuration(value interface{}) (string, error) {
+	var d time.Duration
+	switch v := value.(type) {
+		case time.Duration:
+			d = v
+		case string:
+			pd, err := time.ParseDuration(v)
+			if err != nil {
+				f, ferr := toFloatValue(v)
+				if ferr != nil {
+					return "", err
+				}
+				pd = time.Duration(f * float64(time.Second))
+			}
+			d = pd
+		default:
+			f, err := toFloat(value)
+			if err != nil {
+				return "", err
+			}
+			d = time.Duration(f * float64(time.Second))
+	}
+
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	if d < time.Second {
+		return fmt.Sprintf("%s%dms", sign, d / time.Millisecond), nil
+	}
+
+	d = d.Round(time.Second)
+	days := d / (24 * time.Hour)
+	hours := (d % (24 * time.Hour)) / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	seconds := (d % time.Minute) / time.Second
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	if seconds > 0 {
+		parts = append(parts, fmt.Sprintf("%ds", seconds))
+	}
+	return sign + strings.Join(parts, " "), nil
+}
+
+// Pair is a single element returned by sortedPairs.
+type Pair struct {
+	Name         string
+	Value        string
+}
+
+// sortedPairs returns the entries of a map sorted by key:
+// {{ range sortedPairs .labels }}{{ .Name }}={{ .Value }} {{ end }}.
+func sortedPairs(m interface{}) ([]Pair, error) {
+	if m == nil {
+		return nil, nil
+	}
+	iv := reflect.ValueOf(m)
+	if iv.Kind() != reflect.Map {
+		return nil, fmt.Errorf("unknown type - %T", m)
+	}
+	pairs := make([]Pair, 0, iv.Len())
+	for _, key := range iv.MapKeys() {
+		pairs = append(pairs, Pair{Name: toString(key.Interface()), Value: toString(iv.MapIndex(key).Interface())})
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		return pairs[a].Name < pairs[b].Name
+	})
+	return pairs, nil
+}
+
+// dict builds a map from key/value pairs: {{ template "jira.row" dict "name" "host" "value" .labels.host }}.
+func dict(values ...interface{}) (map[string]interface{}, error) {
+	if len(values) % 2 != 0 {
+		return nil, fmt.Errorf("dict requires an even number of arguments")
+	}
+	result := make(map[string]interface{}, len(values) / 2)
+	for i := 0; i < len(values); i += 2 {
+		key, ok := values[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings, got %T", values[i])
+		}
+		result[key] = values[i+1]
+	}
+	return result, nil
+}
+
+// list builds a slice from its arguments: {{ join "," (list "a" "b") }}.
+func list(values ...interface{}) []interface{} {
+	return values
+}
+
+// subFunc returns a - b.
+func subFunc(a, b interface{}) (float64, error) {
+	av, err := toFloatValue(a)
+	if err != nil {
+		return 0, err
+	}
+	bv, err := toFloatValue(b)
+	if err != nil {
+		return 0, err
+	}
+	return av - bv, nil
+}
+
+// mulFunc returns a * b.
+func mulFunc(a, b interface{}) (float64, error) {
+	av, err := toFloatValue(a)
+	if err != nil {
+		return 0, err
+	}
+	bv, err := toFloatValue(b)
+	if err != nil {
+		return 0, err
+	}
+	return av * bv, nil
+}
+
+// divFunc returns a / b.
+func divFunc(a, b interface{}) (float64, error) {
+	av, err := toFloatValue(a)
+	if err != nil {
+		return 0, err
+	}
+	bv, err := toFloatValue(b)
+	if err != nil {
+		return 0, err
+	}
+	if bv == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	return av / bv, nil
+}
+
+// toJson encodes the value as JSON: {{ toJson .labels }}.
+func toJson(value interface{}) (string, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// urlQuery escapes s so it can be placed inside a URL query: {{ urlquery .labels.instance }}.
+func urlQuery(value interface{}) string {
+	return url.QueryEscape(toString(value))
+}
+
+var (
+	jiraMarkupReplacer = strings.NewReplacer(
+		`\`, `\\`, `{`, `\{`, `}`, `\}`, `[`, `\[`, `]`, `\]`, `|`, `\|`,
+		`*`, `\*`, `_`, `\_`, `+`, `\+`, `^`, `\^`, `~`, `\~`, `!`, `\!`, `??`, `\?\?`,
+	)
+	jiraWordPattern = regexp.MustCompile(`\S+`)
+	jiraUrlPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+)
+
+// escapeJiraWord escapes the markup of a single word, URLs are linked by Jira as they are.
+// A dash only marks strikethrough at the start or end of a word, "#" and "-" only start
+// a list at the beginning of a line, so hostnames like web-01 stay readable.
+func escapeJiraWord(word string, lineStart bool) string {
+	if jiraUrlPattern.MatchString(word) {
+		return word
+	}
+	s := jiraMarkupReplacer.Replace(word)
+	if strings.HasPrefix(s, "-") || (lineStart && strings.HasPrefix(s, "#")) {
+		s = `\` + s
+	}
+	if strings.HasSuffix(s, "-") && !strings.HasSuffix(s, `\-`) {
+		s = s[:len(s)-1] + `\-`
+	}
+	return s
+}
+
+// safeJiraMarkup escapes characters that start Jira wiki markup, so label and annotation
+// values are rendered literally: {{ safeJiraMarkup .annotations.description }}.
+func safeJiraMarkup(value interface{}) string {
+	s := toString(value)
+	var b strings.Builder
+	last := 0
+	for _, loc := range jiraWordPattern.FindAllStringIndex(s, -1) {
+		b.WriteString(s[last:loc[0]])
+		line := s[strings.LastIndex(s[:loc[0]], "\n")+1:loc[0]]
+		b.WriteString(escapeJiraWord(s[loc[0]:loc[1]], strings.TrimSpace(line) == ""))
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
@@ -0,0 +1,204 @@
+package template
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTruncate(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	tests := []struct {
+		name     string
+		n        int
+		s        string
+		want     string
+	}{
+		{"shorter than limit", 10, "short", "short"},
+		{"exact limit", 5, "exact", "exact"},
+		{"cut with ellipsis", 8, "truncated text", "trunc..."},
+		{"multibyte input", 5, "привет мир", "пр..."},
+		{"multibyte exact", 6, "привет", "привет"},
+		{"limit below ellipsis", 2, "hello", "he"},
+		{"negative limit", -1, "hello", "hello"},
+		{"summary limit", 255, long, long[:252] + "..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.n, tt.s)
+			if got != tt.want {
+				t.Errorf("truncate(%d, %q) = %q, want %q", tt.n, tt.s, got, tt.want)
+			}
+			if tt.n >= 0 && len([]rune(got)) > tt.n {
+				t.Errorf("truncate(%d) returned %d characters", tt.n, len([]rune(got)))
+			}
+		})
+	}
+}
+
+func TestTimeFuncs(t *testing.T) {
+	if got, err := dateFunc("2006-01-02 15:04", "2021-03-04T05:06:07Z"); err != nil || got != "2021-03-04 05:06" {
+		t.Errorf("date = %q, %v", got, err)
+	}
+	if got, err := dateFunc("2006-01-02", 1614834367); err != nil || got != "2021-03-04" {
+		t.Errorf("date of a unix timestamp = %q, %v", got, err)
+	}
+	if d, err := since(time.Now().Add(-time.Hour).Format(time.RFC3339)); err != nil || d < time.Hour || d > time.Hour + time.Minute {
+		t.Errorf("since = %v, %v", d, err)
+	}
+
+	bad := []interface{}{"yesterday", "2021-03-04 05:06:07", true}
+	for _, value := range bad {
+		if _, err := dateFunc("2006-01-02", value); err == nil {
+			t.Errorf("date(%v) returned no error", value)
+		}
+		if _, err := since(value); err == nil {
+			t.Errorf("since(%v) returned no error", value)
+		}
+	}
+}
+
+func TestHumanizeDuration(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		want     string
+		err      bool
+	}{
+		{90 * time.Second, "1m 30s", false},
+		{93784, "1d 2h 3m 4s", false},
+		{"2h30m", "2h 30m", false},
+		{"45", "45s", false},
+		{-time.Minute, "-1m", false},
+		{500 * time.Millisecond, "500ms", false},
+		{"2021-03-04T05:06:07Z", "", true},
+		{"soon", "", true},
+		{true, "", true},
+	}
+	for _, tt := range tests {
+		got, err := humanizeDuration(tt.value)
+		if (err != nil) != tt.err {
+			t.Errorf("humanizeDuration(%v) error = %v, want error %v", tt.value, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("humanizeDuration(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	var nilMap map[string]string
+	var nilPtr *int
+	tests := []struct {
+		name     string
+		value    interface{}
+		want     interface{}
+	}{
+		{"missing", nil, "ops"},
+		{"empty string", "", "ops"},
+		{"empty slice", []string{}, "ops"},
+		{"nil map", nilMap, "ops"},
+		{"nil pointer", nilPtr, "ops"},
+		{"value", "dev", "dev"},
+		{"zero number", 0, 0},
+		{"false", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := defaultFunc("ops", tt.value); got != tt.want {
+				t.Errorf("default(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDict(t *testing.T) {
+	d, err := dict("name", "host", "value", 1)
+	if err != nil || d["name"] != "host" || d["value"] != 1 {
+		t.Errorf("dict = %v, %v", d, err)
+	}
+	if _, err := dict("name", "host", "value"); err == nil {
+		t.Error("dict with an odd number of arguments returned no error")
+	}
+	if _, err := dict(1, "host"); err == nil {
+		t.Error("dict with a non string key returned no error")
+	}
+}
+
+func TestDiv(t *testing.T) {
+	if got, err := divFunc(10, "4"); err != nil || got != 2.5 {
+		t.Errorf("div = %v, %v", got, err)
+	}
+	if _, err := divFunc(1, 0); err == nil {
+		t.Error("division by zero returned no error")
+	}
+	if _, err := divFunc(1, "0.0"); err == nil {
+		t.Error("division by a zero string returned no error")
+	}
+	if _, err := divFunc("x", 1); err == nil {
+		t.Error("division of a non numeric value returned no error")
+	}
+}
+
+func TestSafeJiraMarkup(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		want     string
+	}{
+		{"plain text", "disk full", "disk full"},
+		{"hostname", "web-01.example-dc.local", "web-01.example-dc.local"},
+		{"url", "https://grafana.example.com/d/a-b?var-host=web-01&x=*#panel_1", "https://grafana.example.com/d/a-b?var-host=web-01&x=*#panel_1"},
+		{"question and hash", "why? issue #42", "why? issue #42"},
+		{"citation", "??source??", `\?\?source\?\?`},
+		{"effects", "*bold* _italic_ +under+ ^sup^ ~sub~", `\*bold\* \_italic\_ \+under\+ \^sup\^ \~sub\~`},
+		{"strikethrough", "-deleted- text", `\-deleted\- text`},
+		{"macro and link", "{code} [link|http://x] !image.png!", `\{code\} \[link\|http://x\] \!image.png\!`},
+		{"list at line start", "# first\n  - second\nnot # a list", "\\# first\n  \\- second\nnot # a list"},
+		{"backslash", `C:\temp`, `C:\\temp`},
+		{"number", 42, "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := safeJiraMarkup(tt.value); got != tt.want {
+				t.Errorf("safeJiraMarkup(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheDNS(t *testing.T) {
+	size := dnsCacheSize
+	dnsCacheSize = 3
+	dnsCache.Lock()
+	dnsCache.entries = make(map[string]dnsEntry)
+	dnsCache.Unlock()
+	t.Cleanup(func() { dnsCacheSize = size })
+
+	now := time.Now()
+	cacheDNS("expired", nil, now.Add(-2*time.Minute), time.Minute)
+	cacheDNS("a", []string{"10.0.0.1"}, now, time.Minute)
+	cacheDNS("b", []string{"10.0.0.2"}, now, 2*time.Minute)
+
+	// The expired entry makes room for the new host
+	cacheDNS("c", []string{"10.0.0.3"}, now, 3*time.Minute)
+	// Without expired entries the entry expiring first is dropped
+	cacheDNS("d", []string{"10.0.0.4"}, now, 4*time.Minute)
+	// Updating a cached host drops nothing
+	cacheDNS("d", []string{"10.0.0.5"}, now, 4*time.Minute)
+
+	dnsCache.Lock()
+	defer dnsCache.Unlock()
+	var hosts []string
+	for host := range dnsCache.entries {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	if strings.Join(hosts, ",") != "b,c,d" {
+		t.Errorf("cached hosts %v, want b, c and d", hosts)
+	}
+	if ips := dnsCache.entries["d"].ips; len(ips) != 1 || ips[0] != "10.0.0.5" {
+		t.Errorf("cached ips of d %v, want the updated address", ips)
+	}
+}
@@ -0,0 +1,422 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Supported values of the description_format receiver option
+const (
+	FormatMarkdown = "markdown"
+	FormatWiki     = "wiki"
+	FormatADF      = "adf"
+)
+
+const (
+	blockParagraph = iota
+	blockHeading
+	blockList
+	blockCode
+	blockTable
+	blockQuote
+	blockRule
+)
+
+type mdBlock struct {
+	kind         int
+	level        int
+	lang         string
+	lines        []string
+	items        []mdListItem
+	rows         [][]string
+	header       bool
+}
+
+type mdListItem struct {
+	level        int
+	ordered      bool
+	text         string
+}
+
+type mdInline struct {
+	text         string
+	strong       bool
+	em           bool
+	code         bool
+	href         string
+}
+
+var (
+	mdHeadingRe  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdListRe     = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	mdFenceRe    = regexp.MustCompile("^\\s*(```|~~~)\\s*([\\w+-]*)")
+	mdRuleRe     = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	mdTableSepRe = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdInlineRe   = regexp.MustCompile("`([^`]+)`|\\[([^\\]]+)\\]\\(([^)\\s]+)\\)|\\*\\*([^*]+)\\*\\*|__([^_]+)__|\\*([^*\\s][^*]*)\\*|\\b_([^_\\s][^_]*)_\\b")
+)
+
+func isTableRow(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "|") && strings.Count(line, "|") >= 2
+}
+
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// parseMarkdown splits the text into blocks of the Markdown subset supported by the converters:
+// headings, paragraphs, nested lists, fenced code, tables, block quotes and horizontal rules.
+func parseMarkdown(text string) []mdBlock {
+	var blocks []mdBlock
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if m := mdFenceRe.FindStringSubmatch(line); m != nil {
+			block := mdBlock{kind: blockCode, lang: m[2]}
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]) {
+					break
+				}
+				block.lines = append(block.lines, lines[i])
+			}
+			blocks = append(blocks, block)
+			continue
+		}
+
+		if m := mdHeadingRe.FindStringSubmatch(line); m != nil {
+			blocks = append(blocks, mdBlock{kind: blockHeading, level: len(m[1]), lines: []string{m[2]}})
+			continue
+		}
+
+		if mdRuleRe.MatchString(line) {
+			blocks = append(blocks, mdBlock{kind: blockRule})
+			continue
+		}
+
+		if isTableRow(line) {
+			block := mdBlock{kind: blockTable}
+			if i+1 < len(lines) && mdTableSepRe.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-") {
+				block.header = true
+				block.rows = append(block.rows, splitTableRow(line))
+				i++
+			} else {
+				block.rows = append(block.rows, splitTableRow(line))
+			}
+			for i+1 < len(lines) && isTableRow(lines[i+1]) {
+				i++
+				block.rows = append(block.rows, splitTableRow(lines[i]))
+			}
+			blocks = append(blocks, block)
+			continue
+		}
+
+		if mdListRe.MatchString(line) {
+			block := mdBlock{kind: blockList}
+			indents := []int{}
+			for ; i < len(lines); i++ {
+				m := mdListRe.FindStringSubmatch(lines[i])
+				if m == nil {
+					// Continuation of the previous item
+					if strings.TrimSpace(lines[i]) != "" && len(block.items) > 0 && strings.HasPrefix(lines[i], " ") {
+						last := &block.items[len(block.items)-1]
+						last.text = last.text + " " + strings.TrimSpace(lines[i])
+						continue
+					}
+					i--
+					break
+				}
+				indent := len(strings.Replace(m[1], "\t", "    ", -1))
+				for len(indents) > 0 && indent < indents[len(indents)-1] {
+					indents = indents[:len(indents)-1]
+				}
+				if len(indents) == 0 || indent > indents[len(indents)-1] {
+					indents = append(indents, indent)
+				}
+				block.items = append(block.items, mdListItem{
+					level:   len(indents),
+					ordered: !strings.ContainsAny(m[2], "-*+"),
+					text:    m[3],
+				})
+			}
+			blocks = append(blocks, block)
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(line), ">") {
+			block := mdBlock{kind: blockQuote}
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				block.lines = append(block.lines, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+			}
+			i--
+			blocks = append(blocks, block)
+			continue
+		}
+
+		block := mdBlock{kind: blockParagraph}
+		for ; i < len(lines); i++ {
+			l := lines[i]
+			if strings.TrimSpace(l) == "" || mdFenceRe.MatchString(l) || mdHeadingRe.MatchString(l) ||
+				mdListRe.MatchString(l) || isTableRow(l) || mdRuleRe.MatchString(l) ||
+				strings.HasPrefix(strings.TrimSpace(l), ">") {
+				break
+			}
+			block.lines = append(block.lines, strings.TrimSpace(l))
+		}
+		i--
+		blocks = append(blocks, block)
+	}
+
+	return blocks
+}
+
+// parseInline splits a line into text runs with their marks.
+func parseInline(text string) []mdInline {
+	var result []mdInline
+	pos := 0
+	for _, m := range mdInlineRe.FindAllStringSubmatchIndex(text, -1) {
+		if m[0] > pos {
+			result = append(result, mdInline{text: text[pos:m[0]]})
+		}
+		switch {
+			case m[2] >= 0:
+				result = append(result, mdInline{text: text[m[2]:m[3]], code: true})
+			case m[4] >= 0:
+				result = append(result, mdInline{text: text[m[4]:m[5]], href: text[m[6]:m[7]]})
+			case m[8] >= 0:
+				result = append(result, mdInline{text: text[m[8]:m[9]], strong: true})
+			case m[10] >= 0:
+				result = append(result, mdInline{text: text[m[10]:m[11]], strong: true})
+			case m[12] >= 0:
+				result = append(result, mdInline{text: text[m[12]:m[13]], em: true})
+			case m[14] >= 0:
+				result = append(result, mdInline{text: text[m[14]:m[15]], em: true})
+		}
+		pos = m[1]
+	}
+	if pos < len(text) {
+		result = append(result, mdInline{text: text[pos:]})
+	}
+	return result
+}
+
+func wikiInline(text string) string {
+	var b strings.Builder
+	for _, in := range parseInline(text) {
+		switch {
+			case in.code:
+				b.WriteString("{{" + in.text + "}}")
+			case in.href != "":
+				b.WriteString("[" + in.text + "|" + in.href + "]")
+			case in.strong:
+				b.WriteString("*" + in.text + "*")
+			case in.em:
+				b.WriteString("_" + in.text + "_")
+			default:
+				b.WriteString(in.text)
+		}
+	}
+	return b.String()
+}
+
+// MarkdownToWiki converts Markdown text to Jira wiki markup.
+func MarkdownToWiki(text string) string {
+	var out []string
+	for _, block := range parseMarkdown(text) {
+		switch block.kind {
+			case blockHeading:
+				out = append(out, fmt.Sprintf("h%d. %s", block.level, wikiInline(block.lines[0])))
+			case blockParagraph:
+				lines := make([]string, len(block.lines))
+				for i, l := range block.lines {
+					lines[i] = wikiInline(l)
+				}
+				out = append(out, strings.Join(lines, "\n"))
+			case blockList:
+				lines := make([]string, len(block.items))
+				markers := []string{}
+				for i, item := range block.items {
+					marker := "*"
+					if item.ordered {
+						marker = "#"
+					}
+					if len(markers) >= item.level {
+						markers = markers[:item.level-1]
+					}
+					for len(markers) < item.level-1 {
+						markers = append(markers, marker)
+					}
+					markers = append(markers, marker)
+					lines[i] = strings.Join(markers, "") + " " + wikiInline(item.text)
+				}
+				out = append(out, strings.Join(lines, "\n"))
+			case blockCode:
+				open := "{code}"
+				if block.lang != "" {
+					open = "{code:" + block.lang + "}"
+				}
+				out = append(out, open + "\n" + strings.Join(block.lines, "\n") + "\n{code}")
+			case blockTable:
+				lines := make([]string, len(block.rows))
+				for i, row := range block.rows {
+					sep := "|"
+					if i == 0 && block.header {
+						sep = "||"
+					}
+					cells := make([]string, len(row))
+					for n, cell := range row {
+						cells[n] = wikiInline(cell)
+					}
+					lines[i] = sep + strings.Join(cells, sep) + sep
+				}
+				out = append(out, strings.Join(lines, "\n"))
+			case blockQuote:
+				lines := make([]string, len(block.lines))
+				for i, l := range block.lines {
+					lines[i] = wikiInline(l)
+				}
+				out = append(out, "{quote}\n" + strings.Join(lines, "\n") + "\n{quote}")
+			case blockRule:
+				out = append(out, "----")
+		}
+	}
+	return strings.Join(out, "\n\n")
+}
+
+func adfText(text string) []interface{} {
+	content := []interface{}{}
+	for _, in := range parseInline(text) {
+		if in.text == "" {
+			continue
+		}
+		node := map[string]interface{}{"type": "text", "text": in.text}
+		var marks []interface{}
+		switch {
+			case in.code:
+				marks = append(marks, map[string]interface{}{"type": "code"})
+			case in.href != "":
+				marks = append(marks, map[string]interface{}{"type": "link", "attrs": map[string]interface{}{"href": in.href}})
+			case in.strong:
+				marks = append(marks, map[string]interface{}{"type": "strong"})
+			case in.em:
+				marks = append(marks, map[string]interface{}{"type": "em"})
+		}
+		if len(marks) > 0 {
+			node["marks"] = marks
+		}
+		content = append(content, node)
+	}
+	return content
+}
+
+func adfParagraph(text string) map[string]interface{} {
+	return map[string]interface{}{"type": "paragraph", "content": adfText(text)}
+}
+
+// adfList builds nested bulletList/orderedList nodes from the flat list of items starting at pos.
+func adfList(items []mdListItem, pos int) (map[string]interface{}, int) {
+	level := items[pos].level
+	listType := "bulletList"
+	if items[pos].ordered {
+		listType = "orderedList"
+	}
+	list := map[string]interface{}{"type": listType}
+	var content []interface{}
+
+	for pos < len(items) && items[pos].level >= level {
+		if items[pos].level > level {
+			var nested map[string]interface{}
+			nested, pos = adfList(items, pos)
+			if len(content) > 0 {
+				last := content[len(content)-1].(map[string]interface{})
+				last["content"] = append(last["content"].([]interface{}), nested)
+			} else {
+				content = append(content, map[string]interface{}{"type": "listItem", "content": []interface{}{nested}})
+			}
+			continue
+		}
+		content = append(content, map[string]interface{}{
+			"type":    "listItem",
+			"content": []interface{}{adfParagraph(items[pos].text)},
+		})
+		pos++
+	}
+
+	list["content"] = content
+	return list, pos
+}
+
+// MarkdownToADF converts Markdown text to an Atlassian Document Format document.
+func MarkdownToADF(text string) map[string]interface{} {
+	content := []interface{}{}
+	for _, block := range parseMarkdown(text) {
+		switch block.kind {
+			case blockHeading:
+				content = append(content, map[string]interface{}{
+					"type":    "heading",
+					"attrs":   map[string]interface{}{"level": block.level},
+					"content": adfText(block.lines[0]),
+				})
+			case blockParagraph:
+				content = append(content, adfParagraph(strings.Join(block.lines, " ")))
+			case blockList:
+				for pos := 0; pos < len(block.items); {
+					var list map[string]interface{}
+					list, pos = adfList(block.items, pos)
+					content = append(content, list)
+				}
+			case blockCode:
+				node := map[string]interface{}{"type": "codeBlock"}
+				if block.lang != "" {
+					node["attrs"] = map[string]interface{}{"language": block.lang}
+				}
+				if len(block.lines) > 0 {
+					node["content"] = []interface{}{map[string]interface{}{"type": "text", "text": strings.Join(block.lines, "\n")}}
+				}
+				content = append(content, node)
+			case blockTable:
+				var rows []interface{}
+				for i, row := range block.rows {
+					cellType := "tableCell"
+					if i == 0 && block.header {
+						cellType = "tableHeader"
+					}
+					var cells []interface{}
+					for _, cell := range row {
+						cells = append(cells, map[string]interface{}{
+							"type":    cellType,
+							"content": []interface{}{adfParagraph(cell)},
+						})
+					}
+					rows = append(rows, map[string]interface{}{"type": "tableRow", "content": cells})
+				}
+				content = append(content, map[string]interface{}{"type": "table", "content": rows})
+			case blockQuote:
+				content = append(content, map[string]interface{}{
+					"type":    "blockquote",
+					"content": []interface{}{adfParagraph(strings.Join(block.lines, " "))},
+				})
+			case blockRule:
+				content = append(content, map[string]interface{}{"type": "rule"})
+		}
+	}
+	return map[string]interface{}{"version": 1, "type": "doc", "content": content}
+}
+
+// markdownToADF is the template function variant of MarkdownToADF returning the document as JSON.
+func markdownToADF(text string) (string, error) {
+	return toJson(MarkdownToADF(text))
+}
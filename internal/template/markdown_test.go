@@ -0,0 +1,154 @@
+package template
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMarkdownToWiki(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		want     string
+	}{
+		{"empty", "", ""},
+		{"blank lines", "\n  \n", ""},
+		{"headings", "# Title\n### Details ###", "h1. Title\n\nh3. Details"},
+		{"heading with marks", "## Host `web-01` is **down**", "h2. Host {{web-01}} is *down*"},
+		{"paragraphs", "first line\nsecond line\n\nnext paragraph", "first line\nsecond line\n\nnext paragraph"},
+		{"emphasis", "**bold** __bold__ *em* _em_", "*bold* *bold* _em_ _em_"},
+		{"underscores within words", "node_exporter_up", "node_exporter_up"},
+		{"inline code", "run `systemctl restart node_exporter`", "run {{systemctl restart node_exporter}}"},
+		{"link", "see [runbook](https://wiki.example.com/runbook)", "see [runbook|https://wiki.example.com/runbook]"},
+		{"bullet list", "- one\n* two\n+ three", "* one\n* two\n* three"},
+		{"ordered list", "1. one\n2) two", "# one\n# two"},
+		{"nested list", "- one\n  - one.a\n    1. one.a.i\n- two", "* one\n** one.a\n**# one.a.i\n* two"},
+		{"ordered list with nested bullets", "1. one\n   - detail\n2. two", "# one\n#* detail\n# two"},
+		{"list item continuation", "- first\n  continued\n- second", "* first continued\n* second"},
+		{"fenced code", "```\nup == 0\n```", "{code}\nup == 0\n{code}"},
+		{"fenced code with language", "```promql\nrate(errors[5m]) > 0\n\n  by (job)\n```", "{code:promql}\nrate(errors[5m]) > 0\n\n  by (job)\n{code}"},
+		{"tilde fence keeps markup", "~~~\n**not bold** # no heading\n~~~", "{code}\n**not bold** # no heading\n{code}"},
+		{"table with header", "| host | state |\n|------|:-----:|\n| web-01 | `down` |", "||host||state||\n|web-01|{{down}}|"},
+		{"table without header", "| a | b |\n| c | d |", "|a|b|\n|c|d|"},
+		{"blockquote", "> quoted **text**\n> second line", "{quote}\nquoted *text*\nsecond line\n{quote}"},
+		{"rule", "above\n\n---\n\nbelow", "above\n\n----\n\nbelow"},
+		{"windows line endings", "# Title\r\ntext", "h1. Title\n\ntext"},
+		{"blocks without blank lines", "text\n# Title\n- item\n> quote", "text\n\nh1. Title\n\n* item\n\n{quote}\nquote\n{quote}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkdownToWiki(tt.text); got != tt.want {
+				t.Errorf("MarkdownToWiki(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownToADF(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		want     string
+	}{
+		{"empty", "", `[]`},
+		{"heading", "## Host **down**", `[
+			{"type":"heading","attrs":{"level":2},"content":[
+				{"type":"text","text":"Host "},
+				{"type":"text","text":"down","marks":[{"type":"strong"}]}
+			]}
+		]`},
+		{"paragraph lines are joined", "first\nsecond", `[
+			{"type":"paragraph","content":[{"type":"text","text":"first second"}]}
+		]`},
+		{"inline marks", "*em* `code` [runbook](https://wiki.example.com/runbook)", `[
+			{"type":"paragraph","content":[
+				{"type":"text","text":"em","marks":[{"type":"em"}]},
+				{"type":"text","text":" "},
+				{"type":"text","text":"code","marks":[{"type":"code"}]},
+				{"type":"text","text":" "},
+				{"type":"text","text":"runbook","marks":[{"type":"link","attrs":{"href":"https://wiki.example.com/runbook"}}]}
+			]}
+		]`},
+		{"nested list", "- one\n  1. one.a\n- two", `[
+			{"type":"bulletList","content":[
+				{"type":"listItem","content":[
+					{"type":"paragraph","content":[{"type":"text","text":"one"}]},
+					{"type":"orderedList","content":[
+						{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"one.a"}]}]}
+					]}
+				]},
+				{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}
+			]}
+		]`},
+		{"list starting nested", "  - deep\n- top", `[
+			{"type":"bulletList","content":[
+				{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"deep"}]}]},
+				{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"top"}]}]}
+			]}
+		]`},
+		{"ordered list", "1. one\n2. two", `[
+			{"type":"orderedList","content":[
+				{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]}]},
+				{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}
+			]}
+		]`},
+		{"fenced code with language", "```go\nfmt.Println(\"**up**\")\n```", `[
+			{"type":"codeBlock","attrs":{"language":"go"},"content":[{"type":"text","text":"fmt.Println(\"**up**\")"}]}
+		]`},
+		{"empty fenced code", "```\n```", `[
+			{"type":"codeBlock"}
+		]`},
+		{"table", "| host | state |\n| --- | --- |\n| web-01 | |", `[
+			{"type":"table","content":[
+				{"type":"tableRow","content":[
+					{"type":"tableHeader","content":[{"type":"paragraph","content":[{"type":"text","text":"host"}]}]},
+					{"type":"tableHeader","content":[{"type":"paragraph","content":[{"type":"text","text":"state"}]}]}
+				]},
+				{"type":"tableRow","content":[
+					{"type":"tableCell","content":[{"type":"paragraph","content":[{"type":"text","text":"web-01"}]}]},
+					{"type":"tableCell","content":[{"type":"paragraph","content":[]}]}
+				]}
+			]}
+		]`},
+		{"blockquote and rule", "> quoted\n> text\n\n***", `[
+			{"type":"blockquote","content":[{"type":"paragraph","content":[{"type":"text","text":"quoted text"}]}]},
+			{"type":"rule"}
+		]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := MarkdownToADF(tt.text)
+			if doc["version"] != 1 || doc["type"] != "doc" {
+				t.Errorf("MarkdownToADF(%q) = %v, want a version 1 doc", tt.text, doc)
+			}
+
+			// Compared as decoded JSON, as the document is sent to Jira
+			data, err := json.Marshal(doc["content"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got, want interface{}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("invalid expected JSON: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("MarkdownToADF(%q) content = %s", tt.text, data)
+			}
+		})
+	}
+}
+
+func TestMarkdownToADFTemplateFunc(t *testing.T) {
+	text, err := markdownToADF("# Title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"content":[{"attrs":{"level":1},"content":[{"text":"Title","type":"text"}],"type":"heading"}],"type":"doc","version":1}`
+	if text != want {
+		t.Errorf("markdownToADF() = %s, want %s", text, want)
+	}
+}
@@ -1,8 +1,14 @@
 package template
 
 import (
+	"fmt"
+	"sort"
+	"time"
 	"bytes"
+	"context"
 	"strings"
+	"io/ioutil"
+	"path/filepath"
 	"text/template"
 	//"crypto/tls"
 	//"github.com/naoina/toml"
@@ -12,8 +18,27 @@ import (
 
 type Template struct {
 	tmpl   *template.Template
+	name   string
+	opts   Options
 }
 
+// Options control how templates are executed
+type Options struct {
+	// Maximum duration of a single execution, no limit when zero
+	Timeout        time.Duration
+	// How long DNS lookup results are cached, no caching when zero
+	DNSCacheTTL    time.Duration
+	// Functions that access the network return an error
+	DisableNetwork bool
+	// Missing map keys are reported as errors instead of rendering the zero value
+	Strict         bool
+	// Maximum size of the output of a single execution in bytes, no limit when zero
+	MaxOutputSize  int
+}
+
+// Template functions that access the network
+var networkFuncs = []string{"lookupIP", "lookupIPV4", "lookupIPV6"}
+
 type Alerts struct {
 	Api          string
 	Login        string
@@ -61,40 +86,208 @@ var funcMap = template.FuncMap{
 	"lookupIPV4":      LookupIPV4,
 	"lookupIPV6":      LookupIPV6,
 	"strQuote":        strQuote,
+	"toUpper":         strings.ToUpper,
+	"toLower":         strings.ToLower,
+	"title":           strings.Title,
+	"join":            joinFunc,
+	"split":           splitFunc,
+	"trimSpace":       strings.TrimSpace,
+	"default":         defaultFunc,
+	"truncate":        truncate,
+	"date":            dateFunc,
+	"since":           since,
+	"humanizeDuration": humanizeDuration,
+	"sortedPairs":     sortedPairs,
+	"dict":            dict,
+	"list":            list,
+	"sub":             subFunc,
+	"mul":             mulFunc,
+	"div":             divFunc,
+	"toJson":          toJson,
+	"urlquery":        urlQuery,
+	"safeJiraMarkup":  safeJiraMarkup,
+	"markdownToJira":  MarkdownToWiki,
+	"markdownToADF":   markdownToADF,
 }
 
 // LoadTemplate reads and parses all templates defined in the given file and constructs a jiralert.Template.
 func LoadTemplate(path string) (*Template, error) {
-	tmpl, err := template.New(path).Option("missingkey=zero").Funcs(funcMap).ParseFiles(path)
-	if err != nil {
-		return nil, err
+	return LoadTemplates([]string{path}, Options{})
+}
+
+// LoadTemplates reads and parses the templates defined in all files matching the given glob patterns.
+// Every template defined in a file is available under its own name and, namespaced by the file name
+// without extension, as "<file>.<name>", so files may define blocks with the same names. When plain
+// names collide, the definition from the file loaded last wins. Files with the same name in different
+// directories would share a namespace and are rejected.
+func LoadTemplates(patterns []string, opts Options) (*Template, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("missing template files")
+	}
+
+	tmpl := template.New(strings.Join(patterns, ",")).Option("missingkey=zero").Funcs(funcMap)
+	namespaces := make(map[string]string)
+
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no template files match %q", pattern)
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			base := filepath.Base(file)
+			namespace := strings.TrimSuffix(base, filepath.Ext(base))
+			if prev, ok := namespaces[namespace]; ok {
+				// A file matched by several patterns is loaded once
+				if prev == filepath.Clean(file) {
+					continue
+				}
+				return nil, fmt.Errorf("template files %q and %q share the namespace %q", prev, file, namespace)
+			}
+			namespaces[namespace] = filepath.Clean(file)
+
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+
+			ft, err := template.New(file).Option("missingkey=zero").Funcs(funcMap).Parse(string(content))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, t := range ft.Templates() {
+				if t.Tree == nil || t.Name() == file {
+					continue
+				}
+				if _, err := tmpl.AddParseTree(t.Name(), t.Tree); err != nil {
+					return nil, err
+				}
+				if _, err := tmpl.AddParseTree(namespace + "." + t.Name(), t.Tree); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return &Template{tmpl: tmpl, name: strings.Join(patterns, ","), opts: opts}, nil
+}
+
+// WithOptions returns a copy of the template set executed with other options.
+func (t *Template) WithOptions(opts Options) *Template {
+	return &Template{tmpl: t.tmpl, name: t.name, opts: opts}
+}
+
+// Options returns the options the template set is executed with.
+func (t *Template) Options() Options {
+	return t.opts
+}
+
+// Name returns the patterns the template set was loaded from.
+func (t *Template) Name() string {
+	return t.name
+}
+
+// networkFuncMap returns the network functions bound to the execution context.
+func (t *Template) networkFuncMap(ctx context.Context) template.FuncMap {
+	funcs := template.FuncMap{}
+
+	if t.opts.DisableNetwork {
+		for _, name := range networkFuncs {
+			name := name
+			funcs[name] = func(string) ([]string, error) {
+				return nil, fmt.Errorf("%s is disabled, network access is not allowed in templates", name)
+			}
+		}
+		return funcs
+	}
+
+	ttl := t.opts.DNSCacheTTL
+	funcs["lookupIP"] = func(data string) []string {
+		return lookupIP(ctx, data, ttl)
+	}
+	funcs["lookupIPV4"] = func(data string) []string {
+		return filterIPs(lookupIP(ctx, data, ttl), ".")
+	}
+	funcs["lookupIPV6"] = func(data string) []string {
+		return filterIPs(lookupIP(ctx, data, ttl), ":")
 	}
-	return &Template{tmpl: tmpl}, nil
+	return funcs
 }
 
 // Execute parses the provided text (or returns it unchanged if not a Go template), associates it with the templates
 // defined in t.tmpl (so they may be referenced and used) and applies the resulting template to the specified data
 // object, returning the output as a string .
 func (t *Template) Execute(text string, data interface{}) (string, error) {
+	return t.ExecuteContext(context.Background(), text, data)
+}
+
+// limitedWriter fails the execution once the context is done or the output exceeds the limit.
+type limitedWriter struct {
+	ctx          context.Context
+	buf          bytes.Buffer
+	limit        int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if w.limit > 0 && w.buf.Len() + len(p) > w.limit {
+		return 0, fmt.Errorf("template output exceeds %d bytes", w.limit)
+	}
+	return w.buf.Write(p)
+}
+
+// ExecuteContext is Execute that stops when the context is done or the execution takes longer than the configured timeout.
+// text/template cannot be interrupted, so the execution itself ends at its next output after the timeout, when the output
+// exceeds MaxOutputSize or when a network function sees the expired context. A template that loops over large data without
+// writing anything keeps running in the background until it finishes.
+func (t *Template) ExecuteContext(ctx context.Context, text string, data interface{}) (string, error) {
 	if !strings.Contains(text, "{{") {
 		return text, nil
 	}
 
+	if t.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.opts.Timeout)
+		defer cancel()
+	}
+
 	tmpl, err := t.tmpl.Clone()
 	if err != nil {
 		return "", err
 	}
+	tmpl.Funcs(t.networkFuncMap(ctx))
 
 	tmpl, err = tmpl.New("").Parse(text)
 	if err != nil {
 		return "", err
 	}
+	if t.opts.Strict {
+		tmpl.Option("missingkey=error")
+	}
 
-	var buf bytes.Buffer
+	done := make(chan error, 1)
+	w := &limitedWriter{ctx: ctx, limit: t.opts.MaxOutputSize}
 
-    if err = tmpl.Execute(&buf, &data); err != nil {
-		return "", err
+	go func() {
+		done <- tmpl.Execute(w, &data)
+	}()
+
+	select {
+		case err = <-done:
+			if err != nil {
+				return "", err
+			}
+		case <-ctx.Done():
+			return "", fmt.Errorf("template execution stopped: %v", ctx.Err())
 	}
-	ret := buf.String()
+
+	ret := w.buf.String()
 	return ret, nil
 }
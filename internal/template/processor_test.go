@@ -0,0 +1,90 @@
+package template
+
+import (
+	"os"
+	"time"
+	"strings"
+	"testing"
+	"io/ioutil"
+	"path/filepath"
+)
+
+func writeTemplate(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTempTemplate(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "test.tmpl")
+	writeTemplate(t, path, content)
+	return path
+}
+
+func TestLoadTemplatesNamespaces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	writeTemplate(t, filepath.Join(dir, "a", "jira.tmpl"), `{{ define "summary" }}a{{ end }}`)
+	writeTemplate(t, filepath.Join(dir, "a", "extra.tmpl"), `{{ define "summary" }}extra{{ end }}`)
+	writeTemplate(t, filepath.Join(dir, "b", "jira.tmpl"), `{{ define "summary" }}b{{ end }}`)
+
+	tmpl, err := LoadTemplates([]string{filepath.Join(dir, "a", "*.tmpl"), filepath.Join(dir, "a", "jira.tmpl")}, Options{})
+	if err != nil {
+		t.Fatalf("loading a file matched twice: %v", err)
+	}
+	for name, want := range map[string]string{"jira.summary": "a", "extra.summary": "extra", "summary": "a"} {
+		got, err := tmpl.Execute(`{{ template "` + name + `" . }}`, nil)
+		if err != nil || got != want {
+			t.Errorf("template %q = %q, %v, want %q", name, got, err, want)
+		}
+	}
+
+	_, err = LoadTemplates([]string{filepath.Join(dir, "a", "*.tmpl"), filepath.Join(dir, "b", "*.tmpl")}, Options{})
+	if err == nil || !strings.Contains(err.Error(), `namespace "jira"`) {
+		t.Errorf("files with the same name in different directories: got error %v", err)
+	}
+}
+
+func TestExecuteLimits(t *testing.T) {
+	tmpl, err := LoadTemplates([]string{writeTempTemplate(t, `{{ define "row" }}{{ . }}{{ template "row" . }}{{ end }}`)}, Options{MaxOutputSize: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := tmpl.Execute(`{{ range .items }}{{ . }}{{ end }}`, map[string]interface{}{"items": []int{1, 2, 3}})
+	if err != nil || got != "123" {
+		t.Errorf("execute = %q, %v", got, err)
+	}
+
+	_, err = tmpl.Execute(`{{ template "row" "x" }}`, nil)
+	if err == nil || !strings.Contains(err.Error(), "exceeds 1000 bytes") {
+		t.Errorf("unbounded output: got error %v", err)
+	}
+
+	done := make(chan struct{})
+	slow := tmpl.WithOptions(Options{Timeout: 10 * time.Millisecond})
+	go func() {
+		_, err = slow.Execute(`{{ template "row" "x" }}`, nil)
+		close(done)
+	}()
+	select {
+		case <-done:
+			if err == nil || !strings.Contains(err.Error(), "stopped") && !strings.Contains(err.Error(), "deadline") {
+				t.Errorf("timeout: got error %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("execution did not stop after the timeout")
+	}
+}
@@ -0,0 +1,183 @@
+package web
+
+import (
+    "os"
+    "fmt"
+    "log"
+    "sync"
+    "time"
+    "strings"
+    "net/http"
+    "io/ioutil"
+    "crypto/tls"
+    "crypto/x509"
+    "crypto/subtle"
+    "golang.org/x/crypto/bcrypt"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+// Paths that always require authentication when it is configured
+var defaultProtectedPaths = []string{"/api/v1/", "/-/reload"}
+
+// Hash compared for unknown users, so the response time does not reveal which users exist
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("jiramanager"), bcrypt.DefaultCost)
+
+type certLoader struct {
+    sync.Mutex
+    certFile     string
+    keyFile      string
+    modTime      time.Time
+    cert         *tls.Certificate
+}
+
+// getCertificate reloads the key pair whenever one of the files has been modified
+func (cl *certLoader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+    cl.Lock()
+    defer cl.Unlock()
+
+    modTime := time.Time{}
+    for _, name := range []string{cl.certFile, cl.keyFile} {
+        info, err := os.Stat(name)
+        if err != nil {
+            if cl.cert != nil {
+                return cl.cert, nil
+            }
+            return nil, err
+        }
+        if info.ModTime().After(modTime) {
+            modTime = info.ModTime()
+        }
+    }
+
+    if cl.cert != nil && !modTime.After(cl.modTime) {
+        return cl.cert, nil
+    }
+
+    cert, err := tls.LoadX509KeyPair(cl.certFile, cl.keyFile)
+    if err != nil {
+        if cl.cert != nil {
+            log.Printf("[error] reload certificate %v", err)
+            return cl.cert, nil
+        }
+        return nil, err
+    }
+    if cl.cert != nil {
+        log.Printf("[info] certificate reloaded: %s", cl.certFile)
+    }
+    cl.cert = &cert
+    cl.modTime = modTime
+
+    return cl.cert, nil
+}
+
+func newTLSConfig(cfg *config.WebTLS) (*tls.Config, error) {
+    cl := &certLoader{ certFile: cfg.CertFile, keyFile: cfg.KeyFile }
+    if _, err := cl.getCertificate(nil); err != nil {
+        return nil, err
+    }
+
+    tlsConfig := &tls.Config{
+        MinVersion:     tls.VersionTLS12,
+        GetCertificate: cl.getCertificate,
+    }
+
+    if cfg.ClientCAFile != "" {
+        pem, err := ioutil.ReadFile(cfg.ClientCAFile)
+        if err != nil {
+            return nil, err
+        }
+        pool := x509.NewCertPool()
+        if !pool.AppendCertsFromPEM(pem) {
+            return nil, fmt.Errorf("no certificates found in %s", cfg.ClientCAFile)
+        }
+        tlsConfig.ClientCAs = pool
+        tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+    }
+
+    return tlsConfig, nil
+}
+
+type authHandler struct {
+    handler      http.Handler
+    config       *config.Web
+    paths        []string
+}
+
+func (h *authHandler) protected(path string) bool {
+    for _, p := range h.paths {
+        if path == strings.TrimSuffix(p, "/") || strings.HasPrefix(path, p) {
+            return true
+        }
+    }
+    return false
+}
+
+func (h *authHandler) authorized(r *http.Request) bool {
+    if len(h.config.BasicAuthUsers) == 0 && len(h.config.BearerTokens) == 0 {
+        return true
+    }
+
+    if user, pass, ok := r.BasicAuth(); ok {
+        hash, ok := h.config.BasicAuthUsers[user]
+        if !ok {
+            hash = string(dummyHash)
+        }
+        if bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)) == nil && ok {
+            return true
+        }
+    }
+
+    auth := r.Header.Get("Authorization")
+    if strings.HasPrefix(auth, "Bearer ") {
+        token := []byte(strings.TrimPrefix(auth, "Bearer "))
+        for _, t := range h.config.BearerTokens {
+            if subtle.ConstantTimeCompare(token, []byte(t)) == 1 {
+                return true
+            }
+        }
+    }
+
+    return false
+}
+
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    if h.protected(r.URL.Path) && !h.authorized(r) {
+        if len(h.config.BasicAuthUsers) > 0 {
+            w.Header().Set("WWW-Authenticate", `Basic realm="jiramanager"`)
+        }
+        w.WriteHeader(401)
+        w.Write([]byte(`{"status":"error","error":"unauthorized"}`))
+        return
+    }
+    h.handler.ServeHTTP(w, r)
+}
+
+// Handler wraps the handler with the authentication configured in the web section
+func Handler(cfg *config.Web, handler http.Handler) http.Handler {
+    if cfg == nil {
+        return handler
+    }
+    paths := append([]string{}, defaultProtectedPaths...)
+    paths = append(paths, cfg.ProtectedPaths...)
+    return &authHandler{ handler: handler, config: cfg, paths: paths }
+}
+
+// ListenAndServe starts the HTTP listener, with TLS if it is configured
+func ListenAndServe(addr string, cfg *config.Web, handler http.Handler) error {
+    server := &http.Server{
+        Addr:     addr,
+        Handler:  Handler(cfg, handler),
+    }
+
+    if cfg == nil || cfg.TLS == nil {
+        return server.ListenAndServe()
+    }
+
+    tlsConfig, err := newTLSConfig(cfg.TLS)
+    if err != nil {
+        return err
+    }
+    server.TLSConfig = tlsConfig
+
+    return server.ListenAndServeTLS("", "")
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "golang.org/x/crypto/bcrypt"
+    "github.com/ltkh/jiramanager/internal/config"
+)
+
+func TestBasicAuth(t *testing.T) {
+    hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+    if err != nil {
+        t.Fatal(err)
+    }
+    cfg := &config.Web{ BasicAuthUsers: map[string]string{"admin": string(hash)} }
+    handler := Handler(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+    tests := []struct {
+        name     string
+        user     string
+        pass     string
+        code     int
+    }{
+        {"valid user", "admin", "secret", 200},
+        {"wrong password", "admin", "wrong", 401},
+        {"unknown user", "nobody", "secret", 401},
+        {"unknown user with dummy password", "nobody", "jiramanager", 401},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            req := httptest.NewRequest("GET", "/api/v1/issues", nil)
+            req.SetBasicAuth(tt.user, tt.pass)
+            w := httptest.NewRecorder()
+            handler.ServeHTTP(w, req)
+            if w.Code != tt.code {
+                t.Errorf("got status %d, want %d", w.Code, tt.code)
+            }
+        })
+    }
+}
+
+func TestProtectedPaths(t *testing.T) {
+    cfg := &config.Web{ BearerTokens: []string{"token"}, ProtectedPaths: []string{"/metrics"} }
+    handler := Handler(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+    tests := []struct {
+        path     string
+        code     int
+    }{
+        {"/api/v1/alerts", 401},
+        {"/-/reload", 401},
+        {"/metrics", 401},
+        {"/-/healthy", 200},
+    }
+
+    for _, tt := range tests {
+        req := httptest.NewRequest("POST", tt.path, nil)
+        w := httptest.NewRecorder()
+        handler.ServeHTTP(w, req)
+        if w.Code != tt.code {
+            t.Errorf("%s without token: got status %d, want %d", tt.path, w.Code, tt.code)
+        }
+
+        req.Header.Set("Authorization", "Bearer token")
+        w = httptest.NewRecorder()
+        handler.ServeHTTP(w, req)
+        if w.Code != 200 {
+            t.Errorf("%s with token: got status %d, want 200", tt.path, w.Code)
+        }
+    }
+}
@@ -6,6 +6,7 @@ import (
     "os"
     "os/signal"
     "syscall"
+    "strings"
     "runtime"
     "flag"
     "net/http"
@@ -13,6 +14,7 @@ import (
     //"github.com/ltkh/jiramanager/internal/db"
     "github.com/ltkh/jiramanager/internal/config"
     "github.com/ltkh/jiramanager/internal/api/v1"
+    "github.com/ltkh/jiramanager/internal/web"
     //"github.com/ltkh/jiramanager/internal/template"
 )
 
@@ -21,6 +23,11 @@ func main() {
       // Limits the number of operating system threads
     runtime.GOMAXPROCS(runtime.NumCPU())
 
+    // Subcommands
+    if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+        os.Exit(runCommand(os.Args[1:]))
+    }
+
     // Command-line flag parsing
     listen          := flag.String("listen-address", ":9097", "listen address")
     cfFile          := flag.String("config", "config/config.yml", "config file")
@@ -30,6 +37,8 @@ func main() {
     logMaxBackups   := flag.Int("log.max-backups", 3, "log max backups")
     logMaxAge       := flag.Int("log.max-age", 10, "log max age")
     logCompress     := flag.Bool("log.compress", true, "log compress")
+    checkJira       := flag.Bool("check-jira", false, "verify receivers against the Jira metadata on startup")
+    dryRun          := flag.Bool("dry-run", false, "log the issues instead of creating them in Jira")
     flag.Parse()
 
     // Logging settings
@@ -49,18 +58,45 @@ func main() {
         log.Fatalf("[error] %v", err)
     }
 
+    // Verifying receivers against Jira
+    if *checkJira {
+        if errs := v1.CheckJira(cfg); len(errs) > 0 {
+            for _, err := range errs {
+                log.Printf("[error] %v", err)
+            }
+            log.Fatalf("[error] receivers do not match the Jira metadata")
+        }
+    }
+
     // Creating api v1
     apiV1, err := v1.New(cfg)
     if err != nil {
         log.Fatalf("[error] %v", err)
     }
+    if *dryRun {
+        apiV1.SetDryRun(true)
+        log.Print("[info] dry run mode, issues are not created in Jira")
+    }
 
     // Enabled listen port
     http.HandleFunc("/-/healthy", apiV1.ApiHealthy)
+    http.HandleFunc("/-/ready", apiV1.ApiReady)
+    http.HandleFunc("/metrics", apiV1.ApiMetrics)
+    http.HandleFunc("/-/reload", v1.ApiReload(func() error {
+        return reload(apiV1, *cfFile)
+    }))
     http.HandleFunc("/api/v1/alerts", apiV1.ApiAlerts)
+    http.HandleFunc("/api/v1/events", apiV1.ApiEvents)
+    http.HandleFunc("/api/v1/dry_runs", apiV1.ApiDryRuns)
+    http.HandleFunc("/api/v1/limits", apiV1.ApiLimits)
+    http.HandleFunc("/api/v1/silences", apiV1.ApiSilences)
+    http.HandleFunc("/api/v1/silences/", apiV1.ApiSilence)
+    http.HandleFunc("/api/v1/preview", apiV1.ApiPreview)
+    http.HandleFunc("/api/v1/issues", apiV1.ApiIssues)
+    http.HandleFunc("/api/v1/issues/", apiV1.ApiIssue)
 
     go func(){
-        if err := http.ListenAndServe(*listen, nil); err != nil {
+        if err := web.ListenAndServe(*listen, cfg.Web, http.DefaultServeMux); err != nil {
             log.Fatalf("[error] %v", err)
         }
     }()
@@ -76,12 +112,17 @@ func main() {
         os.Exit(0)
     }()
 
+    // Configuration reload signal processing
+    hup := make(chan os.Signal, 1)
+    signal.Notify(hup, syscall.SIGHUP)
+    go func() {
+        for range hup {
+            reload(apiV1, *cfFile)
+        }
+    }()
+
     // Daemon mode
     for {
-        //if err := template.Process(&cfg, client, flTest); err != nil {
-        //    log.Printf("[error] %v", err)
-        //}
-
         // Updating statuses
         if err := apiV1.UpdateStatus(); err != nil {
             log.Printf("[error] update status %v", err)
@@ -91,3 +132,17 @@ func main() {
     }
 }
 
+// reload reads the configuration file with its includes and applies it, the running
+// configuration is kept when the new one is invalid
+func reload(api *v1.Api, filename string) error {
+    cfg, err := config.New(filename)
+    if err == nil {
+        err = api.Reload(cfg)
+    }
+    if err != nil {
+        log.Printf("[error] reload config %v", err)
+        return err
+    }
+    log.Print("[info] config reloaded")
+    return nil
+}